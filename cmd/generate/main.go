@@ -15,15 +15,26 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"strings"
 
 	"github.com/uk-taniyama/go-openapi-spec/pkg/genspec"
 )
 
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
 func main() {
 	config := genspec.Config{}
 
@@ -31,6 +42,13 @@ func main() {
 	flag.StringVar(&config.PackageName, "p", "", "PackageName")
 	flag.StringVar(&config.InputFile, "i", "", "InputFile OpenAPI spec.go file")
 	flag.StringVar(&config.OutputFile, "o", "", "OutputFile ganarated OpenAPI spec")
+	flag.BoolVar(&config.FlattenEmbedded, "flatten", false, "Flatten embedded structs instead of allOf")
+	flag.BoolVar(&config.SplitReadWrite, "split-rw", false, "Generate Input/Output schemas for readOnly/writeOnly properties")
+	flag.BoolVar(&config.StrictTags, "strict", false, "Reject unknown keys in the tags")
+	flag.BoolVar(&config.Check, "check", false, "Compare the spec with OutputFile instead of writing it, fail if it is stale")
+	flag.BoolVar(&config.FailOnInvalid, "fail-on-invalid", false, "Fail when the generated spec is invalid")
+	flag.StringVar((*string)(&config.Format), "format", "", "Output format yaml or json (default by the extension of OutputFile)")
+	flag.Var((*stringList)(&config.Overlays), "overlay", "Overlay file applied to the generated spec (repeatable)")
 	flag.Parse()
 
 	g, err := genspec.NewGenerator(&config)
@@ -38,10 +56,15 @@ func main() {
 		fmt.Fprintln(os.Stderr, "Usage:")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "Error:\n\t%v\n", strings.Join(strings.Split(err.Error(), "\n"), "\n\t"))
-		os.Exit(-1)
+		os.Exit(2)
 	}
 	err = g.Run()
+	var stale *genspec.StaleError
+	if errors.As(err, &stale) {
+		fmt.Print(stale.Diff)
+	}
 	if err != nil {
-		log.Panicln(err.Error())
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
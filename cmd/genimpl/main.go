@@ -15,18 +15,12 @@
 package main
 
 import (
-	"bytes"
 	"flag"
 	"fmt"
-	"go/format"
-	"log"
-	"net/http"
 	"os"
-	"strconv"
-	"text/template"
 
-	"github.com/deepmap/oapi-codegen/pkg/codegen"
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genimpl"
 )
 
 var (
@@ -36,6 +30,11 @@ var (
 	flagTemplateFile string
 )
 
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
 func main() {
 	flag.StringVar(&flagPackageName, "p", "", "Package name")
 	flag.StringVar(&flagTemplateFile, "t", "", "Server template file")
@@ -45,52 +44,22 @@ func main() {
 	if flagPackageName == "" || flagTemplateFile == "" || flagInputFile == "" || flagOutputFile == "" {
 		fmt.Fprintln(os.Stderr, "Usage of genserverimpl:")
 		flag.PrintDefaults()
-		os.Exit(-1)
+		os.Exit(2)
 	}
 
 	swagger, err := openapi3.NewLoader().LoadFromFile(flagInputFile)
 	if err != nil {
-		log.Panic("openapi3.LoadFromFile", err)
-	}
-
-	def, err := codegen.OperationDefinitions(swagger)
-	if err != nil {
-		log.Panic("codegen.OperationDefinitions", err)
-	}
-
-	codegen.TemplateFunctions["package"] = func() string { return flagPackageName }
-	codegen.TemplateFunctions["_"] = func() string { return "" }
-	codegen.TemplateFunctions["getStatusText"] = func(code string) string {
-		n, err := strconv.Atoi(code)
-		if err != nil {
-			return ""
-		}
-		return http.StatusText(n)
+		fail(err)
 	}
-
-	t := template.New("codegen").Funcs(codegen.TemplateFunctions)
-
-	out := bytes.Buffer{}
 	data, err := os.ReadFile(flagTemplateFile)
 	if err != nil {
-		log.Panic("os.ReadFile template", err)
-	}
-	_, err = t.Parse(string(data))
-	if err != nil {
-		log.Panic("template.Parse", err)
+		fail(err)
 	}
-	err = t.Execute(&out, def)
+	src, err := genimpl.Generate(swagger, flagPackageName, string(data))
 	if err != nil {
-		log.Panic("template.Execute", err)
+		fail(err)
 	}
-
-	// Final gofmt.
-	src, err := format.Source(out.Bytes())
-	if err != nil {
-		log.Panicf("format.Source: %v on\n%s", err, src)
-	}
-
 	if err := os.WriteFile(flagOutputFile, src, 0644); err != nil {
-		log.Panicf("os.WriteFile: %v\n", err)
+		fail(err)
 	}
 }
@@ -0,0 +1,133 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genspec"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/project"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/specdiff"
+)
+
+func runChangelog(c *cli, args []string) int {
+	fs := c.newFlagSet()
+	spec := fs.String("spec", "", "spec `file` read at the git refs (default the input of the API of the project file)")
+	title := fs.String("title", "Changelog", "title of the changelog, none if empty")
+	output := fs.String("o", "", "output file (default stdout)")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 2 {
+		return c.usageError(fs, "two specs or two git refs are required")
+	}
+	var configs []*genspec.Config
+	if isFile(fs.Arg(0)) && isFile(fs.Arg(1)) {
+		for _, file := range fs.Args() {
+			configs = append(configs, &genspec.Config{InputFile: file})
+		}
+	} else {
+		config, err := c.changelogConfig(*spec)
+		if err == errUsage {
+			return c.usageError(fs, "no -spec and no %v for the git refs", project.FileName)
+		}
+		if err != nil {
+			return c.fail("changelog", err)
+		}
+		for _, ref := range fs.Args() {
+			data, err := gitShow(ref, config.InputFile)
+			if err != nil {
+				return c.fail("changelog", err)
+			}
+			at := *config
+			at.Source = data
+			configs = append(configs, &at)
+		}
+	}
+	specs := []*openapi3.T{}
+	for _, config := range configs {
+		t, diags, err := loadSpec(config)
+		if err != nil {
+			return c.fail("changelog", err)
+		}
+		if genspec.HasError(diags) {
+			c.printDiagnostics(diags)
+		}
+		specs = append(specs, t)
+	}
+	b := &bytes.Buffer{}
+	err := specdiff.Compare(specs[0], specs[1]).WriteChangelog(b, specs[0], specs[1], *title)
+	if err == nil {
+		err = c.write(*output, b.Bytes())
+	}
+	if err != nil {
+		return c.fail("changelog", err)
+	}
+	return exitOK
+}
+
+func isFile(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && !fi.IsDir()
+}
+
+// changelogConfig returns the config of the spec read at the git refs: the spec file given,
+// or the input of the API of the project file.
+func (c *cli) changelogConfig(spec string) (*genspec.Config, error) {
+	p, err := c.loadProject()
+	if err != nil {
+		return nil, err
+	}
+	if spec != "" {
+		if p == nil {
+			return &genspec.Config{InputFile: spec}, nil
+		}
+		return p.ForInput(spec).Config(), nil
+	}
+	if p == nil {
+		return nil, errUsage
+	}
+	apis, err := p.Select(c.apiName)
+	if err != nil {
+		return nil, err
+	}
+	if len(apis) != 1 {
+		return nil, errors.Errorf("%v: %v apis, select one by -api", p.File, len(apis))
+	}
+	return apis[0].Config(), nil
+}
+
+// gitShow returns the content of file at ref, by git in the directory of file.
+func gitShow(ref, file string) ([]byte, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command("git", "show", ref+":./"+filepath.Base(abs))
+	cmd.Dir = filepath.Dir(abs)
+	cmd.Stderr = stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Errorf("git show %v:%v: %v", ref, file, strings.TrimSpace(stderr.String()))
+	}
+	return data, nil
+}
@@ -0,0 +1,588 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genimpl"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genimport"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genspec"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/project"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/specdiff"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/specdocs"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/speclint"
+)
+
+func isGoFile(file string) bool {
+	return filepath.Ext(file) == ".go"
+}
+
+// printDiagnostics prints diags, without the warnings if quiet.
+func (c *cli) printDiagnostics(diags []genspec.Diagnostic) {
+	for _, d := range diags {
+		if c.quiet && d.Severity != genspec.SeverityError {
+			continue
+		}
+		fmt.Fprintln(c.stderr, d.String())
+	}
+}
+
+// loadSpec loads the spec file of config.InputFile, or config.Source if []byte,
+// or generates the spec of a .go file by config.
+// The problems of the spec are returned as diags, err is returned when the spec cannot be read.
+func loadSpec(config *genspec.Config) (*openapi3.T, []genspec.Diagnostic, error) {
+	file := config.InputFile
+	if isGoFile(file) {
+		return genspec.Generate(context.Background(), config)
+	}
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	var t *openapi3.T
+	var err error
+	if data, ok := config.Source.([]byte); ok {
+		t, err = loader.LoadFromDataWithPath(data, &url.URL{Path: filepath.ToSlash(file)})
+	} else {
+		t, err = loader.LoadFromFile(file)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var diags []genspec.Diagnostic
+	err = t.Validate(loader.Context)
+	if err != nil {
+		diags = append(diags, genspec.Diagnostic{
+			Severity: genspec.SeverityError,
+			Message:  fmt.Sprintf("%v: %v", file, err),
+		})
+	}
+	return t, diags, nil
+}
+
+func runSpec(c *cli, args []string) int {
+	fs := c.newFlagSet()
+	flags := genspec.Config{}
+	fs.BoolVar(&flags.Debug, "d", false, "print the AST of spec.go instead of the spec")
+	fs.StringVar(&flags.PackageName, "p", "", "package name")
+	fs.StringVar(&flags.InputFile, "i", "", "spec.go file")
+	fs.StringVar(&flags.OutputFile, "o", "", "output spec file (default stdout)")
+	fs.BoolVar(&flags.FlattenEmbedded, "flatten", false, "flatten embedded structs instead of allOf")
+	fs.BoolVar(&flags.SplitReadWrite, "split-rw", false, "generate Input/Output schemas for readOnly/writeOnly properties")
+	fs.BoolVar(&flags.StrictTags, "strict", false, "reject unknown keys in the tags")
+	fs.BoolVar(&flags.Check, "check", false, "compare the spec with the output file instead of writing it, exit 1 if it is stale")
+	fs.BoolVar(&flags.FailOnInvalid, "fail-on-invalid", false, "fail when the generated spec is invalid")
+	fs.StringVar((*string)(&flags.Format), "format", "", "output format yaml or json (default by the extension of -o)")
+	fs.StringVar(&flags.OpenAPI, "openapi", "", "OpenAPI version, 3.0.x (default 3.0.0)")
+	fs.Var((*stringList)(&flags.Overlays), "overlay", "overlay `file` applied to the generated spec (repeatable)")
+	watch := false
+	fs.BoolVar(&watch, "watch", false, "regenerate the spec, and impl and client of the project, whenever the files change")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	apis, err := c.inputAPIs(fs, flags.InputFile)
+	if err != nil {
+		return c.apisError(fs, err)
+	}
+	if len(apis) > 1 && flags.OutputFile != "" {
+		return c.usageError(fs, "-o is given for %v APIs", len(apis))
+	}
+	configs := []*genspec.Config{}
+	for _, api := range apis {
+		config := api.Config()
+		setFlags(fs, func(name string) {
+			switch name {
+			case "d":
+				config.Debug = flags.Debug
+			case "check":
+				config.Check = flags.Check
+			case "p":
+				config.PackageName = flags.PackageName
+			case "o":
+				config.OutputFile = flags.OutputFile
+			case "flatten":
+				config.FlattenEmbedded = flags.FlattenEmbedded
+			case "split-rw":
+				config.SplitReadWrite = flags.SplitReadWrite
+			case "strict":
+				config.StrictTags = flags.StrictTags
+			case "fail-on-invalid":
+				config.FailOnInvalid = flags.FailOnInvalid
+			case "format":
+				config.Format = flags.Format
+			case "openapi":
+				config.OpenAPI = flags.OpenAPI
+			case "overlay":
+				config.Overlays = flags.Overlays
+			}
+		})
+		configs = append(configs, config)
+	}
+	if watch {
+		if flags.Check || flags.Debug {
+			return c.usageError(fs, "-watch cannot be used with -check or -d")
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		c.watch(ctx, c.watchTasks(fs, apis, configs), watchInterval, watchDebounce)
+		return exitOK
+	}
+	code := exitOK
+	for _, config := range configs {
+		_, specCode := c.spec(fs, config)
+		code = maxCode(code, specCode)
+	}
+	return code
+}
+
+func maxCode(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// spec generates the spec of config and writes or checks it, the spec is returned when it has no errors.
+func (c *cli) spec(fs *flag.FlagSet, config *genspec.Config) (*openapi3.T, int) {
+	if config.Debug {
+		g, err := genspec.NewGenerator(config)
+		if err != nil {
+			return nil, c.usageError(fs, "%v", err)
+		}
+		err = g.Run()
+		if err != nil {
+			return nil, c.fail("spec", err)
+		}
+		return nil, exitOK
+	}
+
+	spec, diags, err := genspec.Generate(context.Background(), config)
+	if err != nil {
+		return nil, c.fail("spec", err)
+	}
+	c.printDiagnostics(diags)
+	if config.FailOnInvalid && genspec.HasError(diags) {
+		fmt.Fprintf(c.stderr, "spec: generated spec of %v is invalid\n", config.InputFile)
+		return nil, exitFindings
+	}
+	t := spec
+	if genspec.HasError(diags) {
+		// written as before, but not given to impl and client.
+		t = nil
+	}
+	if config.Check {
+		if config.OutputFile == "" {
+			return nil, c.usageError(fs, "-check needs the output file")
+		}
+		diff, err := genspec.CheckFile(spec, config.OutputFile)
+		if err != nil {
+			return nil, c.fail("spec", err)
+		}
+		if diff != "" {
+			fmt.Fprint(c.stdout, diff)
+			fmt.Fprintf(c.stderr, "spec: %v is stale, regenerate it\n", config.OutputFile)
+			return nil, exitFindings
+		}
+		return t, exitOK
+	}
+	format := config.Format
+	if format == "" {
+		format = genspec.FormatOf(config.OutputFile)
+	}
+	out := bytes.Buffer{}
+	err = genspec.WriteTo(&out, spec, format)
+	if err == nil {
+		err = c.write(config.OutputFile, out.Bytes())
+	}
+	if err != nil {
+		return nil, c.fail("spec", err)
+	}
+	return t, exitOK
+}
+
+// loadInput loads the spec of a command generating from a spec, which must not have errors.
+func (c *cli) loadInput(name string, config *genspec.Config) (*openapi3.T, int) {
+	t, diags, err := loadSpec(config)
+	if err != nil {
+		return nil, c.fail(name, err)
+	}
+	c.printDiagnostics(diags)
+	if genspec.HasError(diags) {
+		fmt.Fprintf(c.stderr, "%v: %v is invalid\n", name, config.InputFile)
+		return nil, exitFindings
+	}
+	return t, exitOK
+}
+
+// targetFlags are the flags of a command generating a Go source, overriding impl or client of the project.
+type targetFlags struct {
+	project.Target
+	input string
+}
+
+func (f *targetFlags) define(fs *flag.FlagSet, template bool) {
+	fs.StringVar(&f.Package, "p", "", "package name (default the package of the API)")
+	if template {
+		fs.StringVar(&f.Template, "t", "", "template file")
+	}
+	fs.StringVar(&f.input, "i", "", "spec file, yaml/json or spec.go")
+	fs.StringVar(&f.Output, "o", "", "output Go file (default stdout)")
+}
+
+// targets returns the APIs and their targets, impl or client by get, with the flags applied.
+func (c *cli) targets(fs *flag.FlagSet, flags *targetFlags, get func(*project.API) *project.Target) ([]*project.API, []*project.Target, error) {
+	apis, err := c.inputAPIs(fs, flags.input)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(apis) > 1 && flags.Output != "" {
+		return nil, nil, errors.Errorf("-o is given for %v APIs", len(apis))
+	}
+	selected := []*project.API{}
+	targets := []*project.Target{}
+	for _, api := range apis {
+		t := get(api)
+		if t == nil {
+			if len(apis) > 1 {
+				// the APIs of the project without the target
+				continue
+			}
+			t = &project.Target{}
+		}
+		target := *t
+		target.Package = api.TargetPackage(t)
+		setFlags(fs, func(name string) {
+			switch name {
+			case "p":
+				target.Package = flags.Package
+			case "t":
+				target.Template = flags.Template
+			case "o":
+				target.Output = flags.Output
+			}
+		})
+		if target.Package == "" {
+			return nil, nil, errors.Errorf("no package for %v", api.Input)
+		}
+		selected = append(selected, api)
+		targets = append(targets, &target)
+	}
+	return selected, targets, nil
+}
+
+func runImpl(c *cli, args []string) int {
+	fs := c.newFlagSet()
+	flags := targetFlags{}
+	flags.define(fs, true)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	apis, targets, err := c.targets(fs, &flags, func(api *project.API) *project.Target { return api.Impl })
+	if err != nil {
+		return c.apisError(fs, err)
+	}
+	for i, api := range apis {
+		if targets[i].Template == "" {
+			return c.usageError(fs, "no template for %v", api.Input)
+		}
+	}
+	code := exitOK
+	for i, api := range apis {
+		t, loadCode := c.loadInput("impl", api.Config())
+		code = maxCode(code, loadCode)
+		if t != nil {
+			code = maxCode(code, c.impl(t, targets[i]))
+		}
+		if code == exitError {
+			break
+		}
+	}
+	return code
+}
+
+// impl generates the implementation of the spec by the template of target.
+func (c *cli) impl(t *openapi3.T, target *project.Target) int {
+	text, err := os.ReadFile(target.Template)
+	if err != nil {
+		return c.fail("impl", err)
+	}
+	src, err := genimpl.Generate(t, target.Package, string(text))
+	if err == nil {
+		err = c.write(target.Output, src)
+	}
+	if err != nil {
+		return c.fail("impl", err)
+	}
+	return exitOK
+}
+
+func runClient(c *cli, args []string) int {
+	fs := c.newFlagSet()
+	flags := targetFlags{}
+	flags.define(fs, false)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	apis, targets, err := c.targets(fs, &flags, func(api *project.API) *project.Target { return api.Client })
+	if err != nil {
+		return c.apisError(fs, err)
+	}
+	code := exitOK
+	for i, api := range apis {
+		t, loadCode := c.loadInput("client", api.Config())
+		code = maxCode(code, loadCode)
+		if t != nil {
+			code = maxCode(code, c.client(t, targets[i]))
+		}
+		if code == exitError {
+			break
+		}
+	}
+	return code
+}
+
+// client generates the client of the spec.
+func (c *cli) client(t *openapi3.T, target *project.Target) int {
+	src, err := genimpl.GenerateClient(t, target.Package)
+	if err == nil {
+		err = c.write(target.Output, src)
+	}
+	if err != nil {
+		return c.fail("client", err)
+	}
+	return exitOK
+}
+
+// argAPIs returns the APIs of the files given, with the settings of the project if any, or the APIs of the project.
+func (c *cli) argAPIs(fs *flag.FlagSet) ([]*project.API, error) {
+	p, err := c.loadProject()
+	if err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		if p == nil {
+			return nil, errUsage
+		}
+		return p.Select(c.apiName)
+	}
+	apis := []*project.API{}
+	for _, file := range fs.Args() {
+		if p == nil {
+			apis = append(apis, &project.API{Input: file})
+			continue
+		}
+		apis = append(apis, p.ForInput(file))
+	}
+	return apis, nil
+}
+
+// argConfigs returns the configs of the files given, or of the APIs of the project.
+func (c *cli) argConfigs(fs *flag.FlagSet) ([]*genspec.Config, error) {
+	apis, err := c.argAPIs(fs)
+	if err != nil {
+		return nil, err
+	}
+	configs := []*genspec.Config{}
+	for _, api := range apis {
+		configs = append(configs, api.Config())
+	}
+	return configs, nil
+}
+
+func runValidate(c *cli, args []string) int {
+	fs := c.newFlagSet()
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	configs, err := c.argConfigs(fs)
+	if err != nil {
+		return c.apisError(fs, err)
+	}
+	code := exitOK
+	for _, config := range configs {
+		_, diags, err := loadSpec(config)
+		if err != nil {
+			return c.fail("validate", err)
+		}
+		c.printDiagnostics(diags)
+		if genspec.HasError(diags) {
+			code = exitFindings
+		}
+	}
+	return code
+}
+
+func runLint(c *cli, args []string) int {
+	fs := c.newFlagSet()
+	rules := fs.Bool("rules", false, "list the rules and their default severities")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *rules {
+		for _, r := range speclint.Rules {
+			fmt.Fprintf(c.stdout, "%-24v %-8v %v\n", r.Name, r.Severity, r.Doc)
+		}
+		return exitOK
+	}
+	apis, err := c.argAPIs(fs)
+	if err != nil {
+		return c.apisError(fs, err)
+	}
+	code := exitOK
+	for _, api := range apis {
+		config := api.Config()
+		if !isGoFile(config.InputFile) {
+			return c.usageError(fs, "%v is not a .go file", config.InputFile)
+		}
+		diags, err := lint(config, api.Lint)
+		if err != nil {
+			return c.fail("lint", err)
+		}
+		c.printDiagnostics(diags)
+		if len(diags) > 0 {
+			code = exitFindings
+		}
+	}
+	return code
+}
+
+// lint generates the spec of config, and checks it by the lint rules of the severities when it has no errors.
+func lint(config *genspec.Config, severities map[string]project.Severity) ([]genspec.Diagnostic, error) {
+	g, err := genspec.NewGenerator(config)
+	if err != nil {
+		return nil, err
+	}
+	t, err := g.Generate(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	diags := g.Diagnostics()
+	if genspec.HasError(diags) {
+		return diags, nil
+	}
+	rules := map[string]string{}
+	for name, severity := range severities {
+		rules[name] = string(severity)
+	}
+	problems, err := speclint.Lint(t, g, rules)
+	return append(diags, problems...), err
+}
+
+func runDiff(c *cli, args []string) int {
+	fs := c.newFlagSet()
+	format := fs.String("format", "text", "output format text, json or markdown")
+	failOn := fs.String("fail-on", "breaking", "exit 1 on the changes of breaking, any or none")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 2 {
+		return c.usageError(fs, "two specs are required")
+	}
+	switch *failOn {
+	case "breaking", "any", "none":
+	default:
+		return c.usageError(fs, "unknown -fail-on %q", *failOn)
+	}
+	specs := []*openapi3.T{}
+	for _, file := range fs.Args() {
+		t, diags, err := loadSpec(&genspec.Config{InputFile: file})
+		if err != nil {
+			return c.fail("diff", err)
+		}
+		if genspec.HasError(diags) {
+			c.printDiagnostics(diags)
+		}
+		specs = append(specs, t)
+	}
+	report := specdiff.Compare(specs[0], specs[1])
+	err := report.Write(c.stdout, specdiff.Format(*format))
+	if err != nil {
+		return c.fail("diff", err)
+	}
+	switch {
+	case *failOn == "breaking" && report.Count(specdiff.Breaking) > 0:
+		return exitFindings
+	case *failOn == "any" && len(report.Changes) > 0:
+		return exitFindings
+	}
+	return exitOK
+}
+
+func runImport(c *cli, args []string) int {
+	fs := c.newFlagSet()
+	packageName := fs.String("p", "api", "package name")
+	input := fs.String("i", "", "spec file, yaml or json")
+	output := fs.String("o", "", "output spec.go (default stdout)")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	file, ok := inputArg(fs, *input)
+	if !ok {
+		return c.usageError(fs, "one spec is required")
+	}
+	t, code := c.loadInput("import", &genspec.Config{InputFile: file})
+	if t == nil {
+		return code
+	}
+	src, warnings, err := genimport.Import(t, *packageName)
+	if err != nil {
+		return c.fail("import", err)
+	}
+	if !c.quiet {
+		for _, w := range warnings {
+			fmt.Fprintf(c.stderr, "%v: warning: %v\n", file, w)
+		}
+	}
+	err = c.write(*output, src)
+	if err != nil {
+		return c.fail("import", err)
+	}
+	return exitOK
+}
+
+func runDocs(c *cli, args []string) int {
+	fs := c.newFlagSet()
+	input := fs.String("i", "", "spec file, yaml/json or spec.go")
+	output := fs.String("o", "", "output Markdown file (default stdout)")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	file, ok := inputArg(fs, *input)
+	if !ok {
+		return c.usageError(fs, "one spec is required")
+	}
+	t, code := c.loadInput("docs", &genspec.Config{InputFile: file})
+	if t == nil {
+		return code
+	}
+	md := specdocs.Markdown(t)
+	if !strings.HasSuffix(string(md), "\n") {
+		md = append(md, '\n')
+	}
+	err := c.write(*output, md)
+	if err != nil {
+		return c.fail("docs", err)
+	}
+	return exitOK
+}
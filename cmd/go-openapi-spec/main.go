@@ -0,0 +1,255 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command go-openapi-spec generates, checks and converts OpenAPI specs written in Go.
+//
+// Exit codes: 0 when succeeded, 1 when problems are found, e.g. an invalid spec or differences,
+// and 2 for the errors of usage or I/O.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/uk-taniyama/go-openapi-spec/pkg/project"
+)
+
+const (
+	exitOK       = 0
+	exitFindings = 1
+	exitError    = 2
+)
+
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(c *cli, args []string) int
+}
+
+var commands = []*command{
+	{"spec", "[flags] [spec.go]", "generate the spec from spec.go", runSpec},
+	{"impl", "[flags] [spec]", "generate the server implementation by a template", runImpl},
+	{"client", "[flags] [spec]", "generate the client by oapi-codegen", runClient},
+	{"validate", "[spec...]", "validate specs, spec.go or yaml/json", runValidate},
+	{"diff", "[flags] old new", "classify the changes between two specs as breaking or not", runDiff},
+	{"changelog", "[flags] old new", "write the Markdown changelog between two specs or two git refs", runChangelog},
+	{"lint", "[spec.go...]", "report the problems of spec.go and of its spec by the lint rules", runLint},
+	{"import", "[flags] [spec]", "write spec.go from a spec", runImport},
+	{"docs", "[flags] [spec]", "write the Markdown document of a spec", runDocs},
+}
+
+// cli is the state shared by the commands, set by the global flags.
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+	quiet  bool
+	// the project file and the API selected by -config and -api
+	configFile string
+	apiName    string
+	// the running command
+	cmd *command
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	c := &cli{stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet("go-openapi-spec", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dir := fs.String("C", "", "change to `dir` before running the command")
+	fs.BoolVar(&c.quiet, "q", false, "do not print the warnings")
+	fs.StringVar(&c.configFile, "config", "", "project `file` (default "+project.FileName+" in the working directory or its parents)")
+	fs.StringVar(&c.apiName, "api", "", "run for the API of the `name` in the project file (default all)")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: go-openapi-spec [-C dir] [-q] [-config file] [-api name] <command> [args]\n\nCommands:\n")
+		for _, cmd := range commands {
+			fmt.Fprintf(stderr, "  %-9v %v\n", cmd.name, cmd.short)
+		}
+		fmt.Fprintf(stderr, "\nGlobal flags:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(stderr, "\nRun 'go-openapi-spec <command> -help' for the flags of a command.\n")
+		fmt.Fprintf(stderr, "Without input files, spec, impl, client, validate and lint run for the APIs of the project file,\n")
+		fmt.Fprintf(stderr, "and the flags given override the values of the file.\n")
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+	if *dir != "" {
+		err := os.Chdir(*dir)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+	}
+	name := fs.Arg(0)
+	if name == "help" {
+		if fs.NArg() < 2 {
+			fs.Usage()
+			return exitOK
+		}
+		name = fs.Arg(1)
+		args = []string{"-help"}
+	} else {
+		args = fs.Args()[1:]
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			c.cmd = cmd
+			return cmd.run(c, args)
+		}
+	}
+	fmt.Fprintf(stderr, "go-openapi-spec: unknown command %q\n", name)
+	fs.Usage()
+	return exitError
+}
+
+// newFlagSet returns the FlagSet of the running command, printing its usage to stderr.
+func (c *cli) newFlagSet() *flag.FlagSet {
+	cmd := c.cmd
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: go-openapi-spec %v %v\n\n%v.\n", cmd.name, cmd.args, strings.ToUpper(cmd.short[:1])+cmd.short[1:])
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintf(c.stderr, "\nFlags:\n")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// parseFlags parses args, the exit code is returned when the command must not run.
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK, false
+	}
+	if err != nil {
+		return exitError, false
+	}
+	return exitOK, true
+}
+
+// usageError prints the message and the usage of fs.
+func (c *cli) usageError(fs *flag.FlagSet, format string, args ...interface{}) int {
+	fmt.Fprintf(c.stderr, "%v: %v\n", fs.Name(), fmt.Sprintf(format, args...))
+	fs.Usage()
+	return exitError
+}
+
+// fail prints err of the command and returns exitError.
+func (c *cli) fail(name string, err error) int {
+	fmt.Fprintf(c.stderr, "%v: %v\n", name, err)
+	return exitError
+}
+
+// write writes data to file, or stdout if file is empty.
+func (c *cli) write(file string, data []byte) error {
+	if file == "" {
+		_, err := c.stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// inputArg returns the input file given by the flag or the only argument.
+func inputArg(fs *flag.FlagSet, flagValue string) (string, bool) {
+	switch {
+	case fs.NArg() == 0:
+		return flagValue, flagValue != ""
+	case fs.NArg() == 1 && flagValue == "":
+		return fs.Arg(0), true
+	}
+	return "", false
+}
+
+// loadProject loads the project file given by -config or found from the working directory, nil if none.
+func (c *cli) loadProject() (*project.Project, error) {
+	file := c.configFile
+	if file == "" {
+		var err error
+		file, err = project.Find(".")
+		if err != nil || file == "" {
+			return nil, err
+		}
+	}
+	return project.Load(file)
+}
+
+// inputAPIs returns the APIs to run for: the input given by the flag or the only argument,
+// with the settings of the project file if any, or else the APIs of the project file.
+func (c *cli) inputAPIs(fs *flag.FlagSet, flagValue string) ([]*project.API, error) {
+	input := flagValue
+	switch {
+	case fs.NArg() == 1 && flagValue == "":
+		input = fs.Arg(0)
+	case fs.NArg() > 0:
+		return nil, errUsage
+	}
+	p, err := c.loadProject()
+	if err != nil {
+		return nil, err
+	}
+	if input != "" {
+		if p == nil {
+			return []*project.API{{Input: input}}, nil
+		}
+		return []*project.API{p.ForInput(input)}, nil
+	}
+	if p == nil {
+		return nil, errUsage
+	}
+	return p.Select(c.apiName)
+}
+
+var errUsage = errors.New("usage")
+
+// apisError returns the exit code of an error of inputAPIs.
+func (c *cli) apisError(fs *flag.FlagSet, err error) int {
+	if err == errUsage {
+		return c.usageError(fs, "no input and no %v", project.FileName)
+	}
+	return c.fail(fs.Name(), err)
+}
+
+// setFlags calls fn with the name of each flag set on the command line.
+func setFlags(fs *flag.FlagSet, fn func(name string)) {
+	fs.Visit(func(f *flag.Flag) {
+		fn(f.Name)
+	})
+}
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
@@ -0,0 +1,288 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func runArgs(args ...string) (int, string, string) {
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	code, _, stderr := runArgs()
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr, "Usage: go-openapi-spec")
+
+	code, _, stderr = runArgs("unknown")
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr, `unknown command "unknown"`)
+
+	code, _, stderr = runArgs("spec", "-help")
+	require.Equal(t, exitOK, code)
+	require.Contains(t, stderr, "Usage: go-openapi-spec spec ")
+
+	code, _, stderr = runArgs("help", "docs")
+	require.Equal(t, exitOK, code)
+	require.Contains(t, stderr, "Usage: go-openapi-spec docs ")
+
+	code, _, _ = runArgs("spec", "-unknown")
+	require.Equal(t, exitError, code)
+
+	code, _, _ = runArgs("spec", "no-such-file.go")
+	require.Equal(t, exitError, code)
+}
+
+func TestCommands(t *testing.T) {
+	pet := "../../testdata/pet.yaml"
+
+	code, _, stderr := runArgs("validate", pet)
+	require.Equal(t, exitOK, code, stderr)
+
+	// testdata/pet.spec.yaml is up to date.
+	code, _, stderr = runArgs("spec", "-check", "-o", "../../testdata/pet.spec.yaml", "../../testdata/pet.spec.go")
+	require.Equal(t, exitOK, code, stderr)
+
+	code, _, stderr = runArgs("validate", "../../testdata/pet.spec.go")
+	require.Equal(t, exitFindings, code)
+	require.Contains(t, stderr, `unsupported 'format' value "[a-z][A-Z]"`)
+
+	code, stdout, _ := runArgs("diff", pet, pet)
+	require.Equal(t, exitOK, code)
+	require.Empty(t, stdout)
+
+	spec := filepath.Join(t.TempDir(), "spec.go")
+	code, _, stderr = runArgs("import", "-o", spec, pet)
+	require.Equal(t, exitOK, code, stderr)
+
+	// the spec has no tags and no summaries, reported at the methods.
+	code, _, stderr = runArgs("lint", spec)
+	require.Equal(t, exitFindings, code, stderr)
+	require.Contains(t, stderr, "spec.go:48:2: warning: GET /pets: no tags (operation-tags)\n")
+	require.NotContains(t, stderr, "error")
+
+	// the imported spec changes only operationIds.
+	code, stdout, _ = runArgs("diff", pet, spec)
+	require.Equal(t, exitOK, code)
+	require.Contains(t, stdout, "non-breaking: GET /pets: operationId changed from findPets to FindPets\n")
+	code, _, _ = runArgs("diff", "-fail-on", "any", "-format", "markdown", pet, spec)
+	require.Equal(t, exitFindings, code)
+	code, _, _ = runArgs("diff", "-fail-on", "never", pet, spec)
+	require.Equal(t, exitError, code)
+
+	code, stdout, _ = runArgs("spec", "-format", "json", spec)
+	require.Equal(t, exitOK, code)
+	require.Contains(t, stdout, `"openapi": "3.0.0"`)
+
+	code, stdout, _ = runArgs("docs", pet)
+	require.Equal(t, exitOK, code)
+	require.Contains(t, stdout, "# Swagger Petstore 1.0.0\n")
+
+	code, _, _ = runArgs("impl", "-p", "api", pet)
+	require.Equal(t, exitError, code)
+}
+
+func TestProject(t *testing.T) {
+	dir := t.TempDir()
+	code, _, stderr := runArgs("import", "-o", filepath.Join(dir, "spec.go"), "../../testdata/pet.yaml")
+	require.Equal(t, exitOK, code, stderr)
+	config := filepath.Join(dir, ".openapi-spec.yaml")
+	require.NoError(t, os.WriteFile(config, []byte(`
+defaults:
+  openapi: 3.0.3
+apis:
+- name: pet
+  input: spec.go
+  output: openapi.json
+  package: api
+  client: {output: client.go, package: client}
+`), 0644))
+
+	code, stdout, stderr := runArgs("-config", config, "spec")
+	require.Equal(t, exitOK, code, stderr)
+	require.Empty(t, stdout)
+	data, err := os.ReadFile(filepath.Join(dir, "openapi.json"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"openapi": "3.0.3"`)
+
+	// the flags override the file.
+	code, stdout, _ = runArgs("-config", config, "-api", "pet", "spec", "-o", "", "-openapi", "3.0.1")
+	require.Equal(t, exitOK, code)
+	require.True(t, strings.HasPrefix(stdout, "openapi: 3.0.1\n"))
+
+	code, _, stderr = runArgs("-config", config, "spec", "-check")
+	require.Equal(t, exitOK, code, stderr)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "openapi.json"), []byte(`{"openapi": "3.0.3"}`), 0644))
+	code, stdout, stderr = runArgs("-config", config, "spec", "-check")
+	require.Equal(t, exitFindings, code)
+	require.Contains(t, stdout, "+++ generated\n")
+	require.Contains(t, stderr, "openapi.json is stale, regenerate it")
+
+	code, _, _ = runArgs("-config", config, "-api", "none", "spec")
+	require.Equal(t, exitError, code)
+
+	code, _, stderr = runArgs("-config", config, "client")
+	require.Equal(t, exitOK, code, stderr)
+	data, err = os.ReadFile(filepath.Join(dir, "client.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "package client\n")
+
+	code, _, stderr = runArgs("-config", config, "validate")
+	require.Equal(t, exitOK, code, stderr)
+
+	code, _, stderr = runArgs("-config", config, "impl")
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr, "no template for ")
+}
+
+func TestChangelog(t *testing.T) {
+	pet := "../../testdata/pet.yaml"
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.go")
+	code, _, stderr := runArgs("import", "-o", spec, pet)
+	require.Equal(t, exitOK, code, stderr)
+
+	code, stdout, _ := runArgs("changelog", "-title", "", pet, spec)
+	require.Equal(t, exitOK, code)
+	require.Contains(t, stdout, "- Changed `GET /pets`: Returns all pets from the system that the user has access to\n"+
+		"  - operationId changed from findPets to FindPets\n")
+
+	code, _, _ = runArgs("changelog", pet)
+	require.Equal(t, exitError, code)
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not found")
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	git("add", "spec.go")
+	git("commit", "-q", "-m", "v1")
+	src, err := os.ReadFile(spec)
+	require.NoError(t, err)
+	src = bytes.Replace(src, []byte("// (POST /pets)\n"), []byte("// (POST /pets)\n// summary: Add a pet\n"), 1)
+	src = regexp.MustCompile(`(?s)// deletes a single pet.*?DeletePet\(id int64\)\n`).ReplaceAll(src, nil)
+	require.NoError(t, os.WriteFile(spec, src, 0644))
+	git("commit", "-q", "-a", "-m", "v2")
+
+	code, stdout, stderr = runArgs("changelog", "-spec", spec, "HEAD~1", "HEAD")
+	require.Equal(t, exitOK, code, stderr)
+	require.Equal(t, "# Changelog\n\n## Other\n\n- Removed `DELETE /pets/{id}`: deletes a single pet based on the ID supplied **(breaking)**\n", stdout)
+
+	code, _, stderr = runArgs("changelog", "-spec", spec, "HEAD~1", "unknown")
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr, "git show unknown:")
+}
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "spec.go"), []byte(`// Package api is the pets API.
+//
+//openapi:ignore operation-4xx-response
+package api
+
+const OpenAPISpec = `+"`"+`
+info: {title: pets, version: 1.0.0}
+`+"`"+`
+
+type Pet struct {
+	Name string `+"`json:\"name\"`"+`
+}
+
+type Error struct {
+	Message string `+"`json:\"message\"`"+`
+}
+
+type Interface interface {
+	// List pets.
+	//
+	// (GET /Pets)
+	// summary: List pets
+	// tags: [pets]
+	// 200: pets
+	// default: unexpected error
+	FindPets() []Pet
+
+	// Find a pet.
+	//
+	//openapi:ignore path-casing
+	// (GET /Pets/{id})
+	// tags: [pets]
+	// 200: a pet
+	// default: unexpected error
+	findPet(id int64) Pet
+}
+`), 0644))
+	config := filepath.Join(dir, ".openapi-spec.yaml")
+	require.NoError(t, os.WriteFile(config, []byte(`
+apis:
+- input: spec.go
+  lint: {operation-summary: error, operation-id-casing: off}
+`), 0644))
+
+	code, _, stderr := runArgs("-config", config, "lint")
+	require.Equal(t, exitFindings, code)
+	require.Equal(t, filepath.Join(dir, "spec.go")+":26:2: warning: path /Pets: Pets is not kebab-case (path-casing)\n"+
+		filepath.Join(dir, "spec.go")+":35:2: error: GET /Pets/{id}: no summary (operation-summary)\n", stderr)
+
+	code, stdout, _ := runArgs("lint", "-rules")
+	require.Equal(t, exitOK, code)
+	require.Contains(t, stdout, "operation-description    off      every operation has a description\n")
+
+	require.NoError(t, os.WriteFile(config, []byte("apis: [{input: spec.go, lint: {no-such-rule: error}}]"), 0644))
+	code, _, stderr = runArgs("-config", config, "lint")
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr, `unknown lint rule "no-such-rule"`)
+}
+
+func TestSpecErrors(t *testing.T) {
+	spec := filepath.Join(t.TempDir(), "spec.go")
+	require.NoError(t, os.WriteFile(spec, []byte(`package api
+
+type Error struct {
+	Message string
+}
+
+type Interface interface {
+	// Find pets.
+	FindPets() []Error
+
+	// (GET /pets/{id})
+	// 200: [
+	FindPet(id int64) Error
+}
+`), 0644))
+	code, _, stderr := runArgs("spec", "-fail-on-invalid", spec)
+	require.Equal(t, exitFindings, code)
+	require.Contains(t, stderr, "spec.go:9:2: error: FindPets: no (METHOD /path) in the doc\n")
+	require.Contains(t, stderr, "spec.go:13:2: error: FindPet: (GET /pets/{id}): yaml: ")
+	require.NotContains(t, stderr, "panic")
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genspec"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/project"
+)
+
+// The files are polled, and regenerated after no change for watchDebounce, e.g. a burst of saves.
+const (
+	watchInterval = 300 * time.Millisecond
+	watchDebounce = 500 * time.Millisecond
+)
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// watchTask regenerates when its files change.
+type watchTask struct {
+	name  string
+	files func() []string
+	run   func()
+
+	stamps map[string]fileStamp
+	// the time of the last change not regenerated yet
+	changed time.Time
+}
+
+// watchTasks returns the tasks regenerating the spec, and impl and client of the project, of each API.
+func (c *cli) watchTasks(fs *flag.FlagSet, apis []*project.API, configs []*genspec.Config) []*watchTask {
+	tasks := []*watchTask{}
+	for i, api := range apis {
+		api := api
+		config := configs[i]
+		var impl, client *project.Target
+		if api.Impl != nil {
+			impl = &project.Target{Template: api.Impl.Template, Output: api.Impl.Output, Package: api.TargetPackage(api.Impl)}
+		}
+		if api.Client != nil {
+			client = &project.Target{Output: api.Client.Output, Package: api.TargetPackage(api.Client)}
+		}
+		tasks = append(tasks, &watchTask{
+			name: config.InputFile,
+			files: func() []string {
+				return watchFiles(config, impl, client)
+			},
+			run: func() {
+				// the spec is generated once for impl and client.
+				t, code := c.spec(fs, config)
+				if t == nil || code != exitOK {
+					return
+				}
+				if impl != nil {
+					c.impl(t, impl)
+				}
+				if client != nil {
+					c.client(t, client)
+				}
+			},
+		})
+	}
+	return tasks
+}
+
+// watchFiles returns the .go files of the package of the input, the overlays and the template of impl.
+// The outputs of the spec, impl and client are not watched, they change by the regeneration.
+func watchFiles(config *genspec.Config, impl, client *project.Target) []string {
+	outputs := map[string]bool{}
+	for _, output := range []string{config.OutputFile, targetOutput(impl), targetOutput(client)} {
+		if output != "" {
+			outputs[absPath(output)] = true
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(filepath.Dir(config.InputFile), "*.go"))
+	watched := []string{}
+	for _, file := range files {
+		if !strings.HasSuffix(file, "_test.go") && !outputs[absPath(file)] {
+			watched = append(watched, file)
+		}
+	}
+	watched = append(watched, config.Overlays...)
+	if impl != nil {
+		watched = append(watched, impl.Template)
+	}
+	return watched
+}
+
+func targetOutput(t *project.Target) string {
+	if t == nil {
+		return ""
+	}
+	return t.Output
+}
+
+func absPath(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.Clean(file)
+	}
+	return abs
+}
+
+// stampFiles returns the stamps of the files, without the files that do not exist.
+func stampFiles(files []string) map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err == nil {
+			stamps[file] = fileStamp{fi.ModTime(), fi.Size()}
+		}
+	}
+	return stamps
+}
+
+// watch runs the tasks, and then runs them again on the changes of their files until ctx is done.
+// The errors are printed by the tasks, watch does not stop on them.
+func (c *cli) watch(ctx context.Context, tasks []*watchTask, interval, debounce time.Duration) {
+	for _, t := range tasks {
+		t.stamps = stampFiles(t.files())
+		c.runTask(t)
+	}
+	if !c.quiet {
+		fmt.Fprintln(c.stderr, "watching for changes, interrupt to stop")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, t := range tasks {
+				stamps := stampFiles(t.files())
+				if !reflect.DeepEqual(stamps, t.stamps) {
+					t.stamps = stamps
+					t.changed = now
+					continue
+				}
+				if t.changed.IsZero() || now.Sub(t.changed) < debounce {
+					continue
+				}
+				t.changed = time.Time{}
+				if !c.quiet {
+					fmt.Fprintf(c.stderr, "%v: regenerating\n", t.name)
+				}
+				c.runTask(t)
+			}
+		}
+	}
+}
+
+// runTask runs t, printing a panic of the generators instead of stopping the watch.
+func (c *cli) runTask(t *watchTask) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(c.stderr, "%v: panic: %v\n", t.name, r)
+		}
+	}()
+	t.run()
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genspec"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/project"
+)
+
+func TestWatchDebounce(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "spec.go")
+	require.NoError(t, os.WriteFile(file, []byte("package api\n"), 0644))
+
+	runs := make(chan int, 10)
+	n := 0
+	task := &watchTask{
+		name:  file,
+		files: func() []string { return []string{file} },
+		run: func() {
+			n++
+			runs <- n
+		},
+	}
+	c := &cli{stdout: &bytes.Buffer{}, stderr: &bytes.Buffer{}, quiet: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.watch(ctx, []*watchTask{task}, 5*time.Millisecond, 100*time.Millisecond)
+		close(done)
+	}()
+	require.Equal(t, 1, <-runs)
+
+	// a burst of saves regenerates once.
+	for i := 0; i < 5; i++ {
+		require.NoError(t, os.WriteFile(file, []byte("package api\n"+strings.Repeat("\n", i+1)), 0644))
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case got := <-runs:
+		require.Equal(t, 2, got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("not regenerated")
+	}
+	select {
+	case <-runs:
+		t.Fatal("regenerated twice")
+	case <-time.After(200 * time.Millisecond):
+	}
+	cancel()
+	<-done
+}
+
+func TestWatchTasks(t *testing.T) {
+	dir := t.TempDir()
+	code, _, stderr := runArgs("import", "-o", filepath.Join(dir, "spec.go"), "../../testdata/pet.yaml")
+	require.Equal(t, exitOK, code, stderr)
+	config := filepath.Join(dir, project.FileName)
+	require.NoError(t, os.WriteFile(config, []byte(`
+apis:
+- input: spec.go
+  output: openapi.yaml
+  package: api
+  client: {output: client/client.go, package: client}
+`), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "client"), 0755))
+	p, err := project.Load(config)
+	require.NoError(t, err)
+
+	stderrBuf := &bytes.Buffer{}
+	c := &cli{stdout: &bytes.Buffer{}, stderr: stderrBuf, quiet: true}
+	tasks := c.watchTasks(flag.NewFlagSet("spec", flag.ContinueOnError), p.APIs, []*genspec.Config{p.APIs[0].Config()})
+	require.Len(t, tasks, 1)
+	require.Equal(t, []string{filepath.Join(dir, "spec.go")}, tasks[0].files())
+
+	tasks[0].run()
+	require.Empty(t, stderrBuf.String())
+	_, err = os.Stat(filepath.Join(dir, "client", "client.go"))
+	require.NoError(t, err)
+
+	src, err := os.ReadFile(filepath.Join(dir, "spec.go"))
+	require.NoError(t, err)
+	src = bytes.Replace(src, []byte("type Error struct {\n"), []byte("type Error struct {\n\tDetail string `json:\"detail\"`\n"), 1)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "spec.go"), src, 0644))
+	tasks[0].run()
+	data, err := os.ReadFile(filepath.Join(dir, "openapi.yaml"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "detail:")
+}
+
+func TestWatchOutputsInPackage(t *testing.T) {
+	dir := t.TempDir()
+	code, _, stderr := runArgs("import", "-o", filepath.Join(dir, "spec.go"), "../../testdata/pet.yaml")
+	require.Equal(t, exitOK, code, stderr)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server.tmpl"), []byte("package {{package}}\n\n// {{len .}} operations\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "types.go"), []byte("package api\n"), 0644))
+	config := filepath.Join(dir, project.FileName)
+	require.NoError(t, os.WriteFile(config, []byte(`
+apis:
+- input: spec.go
+  output: openapi.yaml
+  package: api
+  impl: {template: server.tmpl, output: server.gen.go}
+  client: {output: client.gen.go}
+`), 0644))
+	p, err := project.Load(config)
+	require.NoError(t, err)
+
+	stderrBuf := &bytes.Buffer{}
+	c := &cli{stdout: &bytes.Buffer{}, stderr: stderrBuf}
+	tasks := c.watchTasks(flag.NewFlagSet("spec", flag.ContinueOnError), p.APIs, []*genspec.Config{p.APIs[0].Config()})
+	require.Equal(t, []string{
+		filepath.Join(dir, "spec.go"),
+		filepath.Join(dir, "types.go"),
+		filepath.Join(dir, "server.tmpl"),
+	}, tasks[0].files())
+
+	// the outputs written next to spec.go do not trigger the regeneration.
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	c.watch(ctx, tasks, 5*time.Millisecond, 20*time.Millisecond)
+	require.NotContains(t, stderrBuf.String(), "regenerating")
+	for _, file := range []string{"server.gen.go", "client.gen.go", "openapi.yaml"} {
+		_, err = os.Stat(filepath.Join(dir, file))
+		require.NoError(t, err, file)
+	}
+}
+
+func TestWatchRecover(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	c := &cli{stdout: &bytes.Buffer{}, stderr: stderr}
+	c.runTask(&watchTask{name: "spec.go", run: func() { panic("broken") }})
+	require.Equal(t, "spec.go: panic: broken\n", stderr.String())
+}
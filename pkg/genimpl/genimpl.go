@@ -0,0 +1,94 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package genimpl generates Go sources from an OpenAPI spec, by the templates of oapi-codegen.
+package genimpl
+
+import (
+	"bytes"
+	"go/format"
+	"net/http"
+	"strconv"
+	"text/template"
+
+	"github.com/deepmap/oapi-codegen/pkg/codegen"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+// resolveRefs resolves the $refs of spec, which are not resolved in a spec from genspec.
+func resolveRefs(spec *openapi3.T) error {
+	err := openapi3.NewLoader().ResolveRefsIn(spec, nil)
+	return errors.Wrap(err, "ResolveRefsIn")
+}
+
+// Generate executes the template text with the operation definitions of spec, and formats the result.
+// Besides the functions of oapi-codegen, the template can use package, _ and getStatusText.
+func Generate(spec *openapi3.T, packageName string, text string) ([]byte, error) {
+	err := resolveRefs(spec)
+	if err != nil {
+		return nil, err
+	}
+	def, err := codegen.OperationDefinitions(spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "codegen.OperationDefinitions")
+	}
+
+	funcs := template.FuncMap{}
+	for k, v := range codegen.TemplateFunctions {
+		funcs[k] = v
+	}
+	funcs["package"] = func() string { return packageName }
+	funcs["_"] = func() string { return "" }
+	funcs["getStatusText"] = func(code string) string {
+		n, err := strconv.Atoi(code)
+		if err != nil {
+			return ""
+		}
+		return http.StatusText(n)
+	}
+
+	t, err := template.New("codegen").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "template.Parse")
+	}
+	out := bytes.Buffer{}
+	err = t.Execute(&out, def)
+	if err != nil {
+		return nil, errors.Wrap(err, "template.Execute")
+	}
+
+	// Final gofmt.
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(err, "format.Source on\n%s", out.Bytes())
+	}
+	return src, nil
+}
+
+// GenerateClient generates the types and the client of spec by oapi-codegen.
+func GenerateClient(spec *openapi3.T, packageName string) ([]byte, error) {
+	err := resolveRefs(spec)
+	if err != nil {
+		return nil, err
+	}
+	src, err := codegen.Generate(spec, packageName, codegen.Options{
+		GenerateClient: true,
+		GenerateTypes:  true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "codegen.Generate")
+	}
+	return []byte(src), nil
+}
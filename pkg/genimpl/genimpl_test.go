@@ -0,0 +1,44 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genimpl_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/require"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genimpl"
+)
+
+func TestGenerate(t *testing.T) {
+	spec, err := openapi3.NewLoader().LoadFromFile("../../testdata/pet.yaml")
+	require.NoError(t, err)
+
+	text, err := os.ReadFile("../../templates/serverimpl.go.tmpl")
+	require.NoError(t, err)
+	src, err := genimpl.Generate(spec, "api", string(text))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(src), "package api\n"))
+	require.Contains(t, string(src), "FindPets")
+
+	_, err = genimpl.Generate(spec, "api", "package {{package}}\nfunc {")
+	require.Error(t, err)
+
+	src, err = genimpl.GenerateClient(spec, "client")
+	require.NoError(t, err)
+	require.Contains(t, string(src), "func NewClientWithResponses(")
+}
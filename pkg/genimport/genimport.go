@@ -0,0 +1,532 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package genimport writes a spec.go from an OpenAPI spec, the reverse of genspec.
+//
+// The object schemas become structs, the other schemas named types, and the operations
+// the methods of an interface. What cannot be written in Go, e.g. oneOf or header parameters,
+// is kept in OpenAPISpec (components are merged by genspec) or reported as a warning.
+package genimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/iancoleman/strcase"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const schemaRefPrefix = "#/components/schemas/"
+
+type importer struct {
+	spec     *openapi3.T
+	warnings []string
+	imports  map[string]bool
+	// component name -> Go type name, for the schemas written in Go
+	names map[string]string
+	// the schemas kept in OpenAPISpec
+	kept  map[string]bool
+	types bytes.Buffer
+}
+
+// Import returns the source of spec.go for spec, and the warnings of what is lost.
+func Import(spec *openapi3.T, packageName string) ([]byte, []string, error) {
+	im := &importer{
+		spec:    spec,
+		imports: map[string]bool{},
+		names:   map[string]string{},
+		kept:    map[string]bool{},
+	}
+	names := sortedKeys(spec.Components.Schemas)
+	for _, name := range names {
+		if !im.expressible(spec.Components.Schemas[name], map[string]bool{}) {
+			im.kept[name] = true
+			continue
+		}
+		im.names[name] = goName(name)
+	}
+	for _, name := range names {
+		if !im.kept[name] {
+			im.writeSchema(name, spec.Components.Schemas[name].Value)
+		}
+	}
+	ops := im.operations()
+	if len(spec.Paths) > 0 && im.names["Error"] != "Error" {
+		im.warnf("schema Error is not declared in Go, the default responses refer to it")
+	}
+
+	root, err := im.openAPISpec()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := bytes.Buffer{}
+	fmt.Fprintf(&out, "// +build ignore\n\npackage %v\n\n", packageName)
+	if len(im.imports) > 0 {
+		for _, p := range sortedKeys(im.imports) {
+			fmt.Fprintf(&out, "import %q\n", p)
+		}
+		out.WriteString("\n")
+	}
+	fmt.Fprintf(&out, "const OpenAPISpec = `\n%v`\n\n", root)
+	out.Write(im.types.Bytes())
+	out.Write(ops)
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "format.Source on\n%s", out.Bytes())
+	}
+	return src, im.warnings, nil
+}
+
+func (im *importer) warnf(format string, args ...interface{}) {
+	im.warnings = append(im.warnings, fmt.Sprintf(format, args...))
+}
+
+// openAPISpec returns the YAML of the root, with the components that are not written in Go.
+func (im *importer) openAPISpec() (string, error) {
+	kv := map[string]interface{}{}
+	b, err := json.Marshal(im.spec)
+	if err != nil {
+		return "", err
+	}
+	err = json.Unmarshal(b, &kv)
+	if err != nil {
+		return "", err
+	}
+	delete(kv, "openapi")
+	delete(kv, "paths")
+	components, _ := kv["components"].(map[string]interface{})
+	if components != nil {
+		schemas, _ := components["schemas"].(map[string]interface{})
+		for name := range schemas {
+			if !im.kept[name] {
+				delete(schemas, name)
+			}
+		}
+		for k, v := range components {
+			m, ok := v.(map[string]interface{})
+			if ok && len(m) == 0 {
+				delete(components, k)
+			}
+		}
+		if len(components) == 0 {
+			delete(kv, "components")
+		}
+	}
+	text, err := yaml.Marshal(kv)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(string(text), "`") {
+		return "", errors.New("OpenAPISpec cannot contain a backquote")
+	}
+	return string(text), nil
+}
+
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func goName(name string) string {
+	n := strcase.ToCamel(name)
+	if !identPattern.MatchString(n) || token.Lookup(n).IsKeyword() {
+		n = "X" + n
+	}
+	return n
+}
+
+// expressible reports whether the schema can be written in Go.
+func (im *importer) expressible(ref *openapi3.SchemaRef, visiting map[string]bool) bool {
+	if ref == nil {
+		return true
+	}
+	if ref.Ref != "" {
+		return strings.HasPrefix(ref.Ref, schemaRefPrefix)
+	}
+	s := ref.Value
+	if s == nil || len(s.OneOf) > 0 || len(s.AnyOf) > 0 || s.Not != nil || s.Discriminator != nil {
+		return false
+	}
+	for _, p := range s.AllOf {
+		if !im.expressible(p, visiting) {
+			return false
+		}
+		if p.Ref == "" && (p.Value == nil || p.Value.Type != "object" && len(p.Value.Properties) == 0) {
+			return false
+		}
+	}
+	if len(s.AllOf) > 0 && s.Type != "" && s.Type != "object" {
+		return false
+	}
+	if s.AdditionalProperties != nil && len(s.Properties) > 0 {
+		return false
+	}
+	for _, p := range s.Properties {
+		if !im.expressible(p, visiting) {
+			return false
+		}
+	}
+	return im.expressible(s.Items, visiting) && im.expressible(s.AdditionalProperties, visiting)
+}
+
+// goType returns the Go type of ref, and the keys of the schema it expresses.
+func (im *importer) goType(ref *openapi3.SchemaRef) (string, []string) {
+	if ref == nil {
+		return "interface{}", nil
+	}
+	if ref.Ref != "" {
+		name := strings.TrimPrefix(ref.Ref, schemaRefPrefix)
+		n, ok := im.names[name]
+		if !ok {
+			// kept in OpenAPISpec, genspec refers to it by the name.
+			n = name
+		}
+		return n, nil
+	}
+	s := ref.Value
+	if len(s.AllOf) == 1 && s.AllOf[0].Ref != "" {
+		t, _ := im.goType(s.AllOf[0])
+		return t, []string{"allOf"}
+	}
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			im.imports["time"] = true
+			return "time.Time", []string{"type", "format"}
+		case "byte":
+			return "[]byte", []string{"type", "format"}
+		}
+		return "string", []string{"type"}
+	case "integer":
+		switch s.Format {
+		case "int32", "int64":
+			return s.Format, []string{"type", "format"}
+		}
+		return "int", []string{"type"}
+	case "number":
+		switch s.Format {
+		case "float":
+			return "float32", []string{"type", "format"}
+		case "double":
+			return "float64", []string{"type", "format"}
+		}
+		return "float64", []string{"type"}
+	case "boolean":
+		return "bool", []string{"type"}
+	case "array":
+		t, _ := im.goType(s.Items)
+		return "[]" + t, []string{"type", "items"}
+	}
+	if s.AdditionalProperties != nil {
+		t, _ := im.goType(s.AdditionalProperties)
+		return "map[string]" + t, []string{"type", "additionalProperties"}
+	}
+	if len(s.Properties) > 0 || len(s.AllOf) > 0 {
+		b := bytes.Buffer{}
+		b.WriteString("struct {\n")
+		im.writeFields(&b, s)
+		b.WriteString("}")
+		return b.String(), []string{"type", "properties", "required", "allOf"}
+	}
+	if s.Type == "object" {
+		return "map[string]interface{}", []string{"type"}
+	}
+	return "interface{}", nil
+}
+
+// schemeTag returns the keys of s not expressed by the Go type, as a JSON for the scheme tag.
+func schemeTag(s *openapi3.Schema, expressed []string) string {
+	if s == nil {
+		return ""
+	}
+	kv := map[string]interface{}{}
+	b, _ := json.Marshal(s)
+	json.Unmarshal(b, &kv)
+	for _, k := range expressed {
+		delete(kv, k)
+	}
+	if len(kv) == 0 {
+		return ""
+	}
+	b, _ = json.Marshal(kv)
+	if strings.Contains(string(b), "`") {
+		return ""
+	}
+	return string(b)
+}
+
+func (im *importer) writeSchema(name string, s *openapi3.Schema) {
+	w := &im.types
+	goName := im.names[name]
+	if goName != name {
+		im.warnf("schema %v is renamed to %v", name, goName)
+	}
+	writeDoc(w, s.Description)
+	if s.Type == "object" || len(s.Properties) > 0 || len(s.AllOf) > 0 {
+		if s.AdditionalProperties == nil {
+			fmt.Fprintf(w, "type %v struct {\n", goName)
+			im.writeFields(w, s)
+			w.WriteString("}\n\n")
+			return
+		}
+	}
+	t, expressed := im.goType(&openapi3.SchemaRef{Value: s})
+	fmt.Fprintf(w, "type %v %v\n\n", goName, t)
+	if len(s.Enum) > 0 && (s.Type == "string" || s.Type == "integer") {
+		im.writeEnum(goName, s)
+		expressed = append(expressed, "enum", "x-enum-varnames", "x-enum-descriptions")
+	}
+	if tag := schemeTag(s, append(expressed, "description")); tag != "" {
+		im.warnf("schema %v: %v are lost", name, tag)
+	}
+}
+
+func (im *importer) writeEnum(goName string, s *openapi3.Schema) {
+	w := &im.types
+	varnames := []string{}
+	raw, ok := s.Extensions["x-enum-varnames"].(json.RawMessage)
+	if ok {
+		json.Unmarshal(raw, &varnames)
+	}
+	w.WriteString("const (\n")
+	for i, v := range s.Enum {
+		name := goName + goName2(fmt.Sprintf("%v", v))
+		if i < len(varnames) {
+			name = varnames[i]
+		}
+		value := fmt.Sprintf("%v", v)
+		if s.Type == "string" {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(w, "\t%v %v = %v\n", name, goName, value)
+	}
+	w.WriteString(")\n\n")
+}
+
+func goName2(value string) string {
+	n := strcase.ToCamel(value)
+	if !identPattern.MatchString("X" + n) {
+		return strconv.Itoa(len(value))
+	}
+	return n
+}
+
+func (im *importer) writeFields(w *bytes.Buffer, s *openapi3.Schema) {
+	for _, p := range s.AllOf {
+		if p.Ref != "" {
+			t, _ := im.goType(p)
+			fmt.Fprintf(w, "\t%v\n", t)
+			continue
+		}
+		im.writeFields(w, p.Value)
+	}
+	required := map[string]bool{}
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	for _, name := range sortedKeys(s.Properties) {
+		prop := s.Properties[name]
+		t, expressed := im.goType(prop)
+		tags := []string{}
+		if required[name] {
+			tags = append(tags, fmt.Sprintf("json:%q", name))
+		} else {
+			tags = append(tags, fmt.Sprintf("json:%q", name+",omitempty"), `validate:"omitempty"`)
+		}
+		if tag := schemeTag(prop.Value, expressed); tag != "" && prop.Ref == "" {
+			tags = append(tags, "scheme:"+strconv.Quote(tag))
+		}
+		fmt.Fprintf(w, "\t%v %v `%v`\n", goName(name), t, strings.Join(tags, " "))
+	}
+}
+
+func writeDoc(w *bytes.Buffer, doc string) {
+	doc = strings.TrimSpace(doc)
+	if doc == "" {
+		return
+	}
+	for _, l := range strings.Split(doc, "\n") {
+		fmt.Fprintf(w, "// %v\n", strings.TrimRight(l, " "))
+	}
+}
+
+func (im *importer) operations() []byte {
+	w := bytes.Buffer{}
+	params := bytes.Buffer{}
+	w.WriteString("type Interface interface {\n")
+	for _, path := range sortedKeys(im.spec.Paths) {
+		item := im.spec.Paths[path]
+		for _, method := range sortedKeys(item.Operations()) {
+			ope := item.Operations()[method]
+			im.writeOperation(&w, &params, path, method, ope, item.Parameters)
+		}
+	}
+	w.WriteString("}\n\n")
+	w.Write(params.Bytes())
+	return w.Bytes()
+}
+
+func (im *importer) writeOperation(w, params *bytes.Buffer, path, method string, ope *openapi3.Operation, common openapi3.Parameters) {
+	name := ope.OperationID
+	if name == "" {
+		name = strings.ToLower(method) + " " + strings.NewReplacer("/", " ", "{", "", "}", "").Replace(path)
+	}
+	name = goName(name)
+	where := fmt.Sprintf("%v %v", method, path)
+
+	args := []string{}
+	query := openapi3.Schema{Type: "object", Properties: openapi3.Schemas{}}
+	descriptions := map[string]string{}
+	for _, p := range append(append(openapi3.Parameters{}, common...), ope.Parameters...) {
+		if p.Value == nil {
+			im.warnf("%v: parameter %v is not supported", where, p.Ref)
+			continue
+		}
+		switch p.Value.In {
+		case "path":
+			if !identPattern.MatchString(p.Value.Name) {
+				im.warnf("%v: path parameter %v is not a Go identifier", where, p.Value.Name)
+				continue
+			}
+			t, _ := im.goType(p.Value.Schema)
+			args = append(args, p.Value.Name+" "+t)
+		case "query":
+			query.Properties[p.Value.Name] = p.Value.Schema
+			descriptions[p.Value.Name] = p.Value.Description
+			if p.Value.Required {
+				query.Required = append(query.Required, p.Value.Name)
+			}
+		default:
+			im.warnf("%v: %v parameter %v is not supported", where, p.Value.In, p.Value.Name)
+		}
+	}
+	if len(query.Properties) > 0 {
+		pname := name + "Params"
+		fmt.Fprintf(params, "type %v struct {\n", pname)
+		for _, n := range sortedKeys(query.Properties) {
+			prop := query.Properties[n]
+			t, expressed := im.goType(prop)
+			tags := []string{fmt.Sprintf("json:%q", n)}
+			for _, r := range query.Required {
+				if r == n {
+					tags = append(tags, `validate:"required"`)
+				}
+			}
+			if tag := schemeTag(prop.Value, expressed); tag != "" && prop.Ref == "" {
+				tags = append(tags, "scheme:"+strconv.Quote(tag))
+			}
+			writeDoc(params, descriptions[n])
+			fmt.Fprintf(params, "\t%v %v `%v`\n", goName(n), t, strings.Join(tags, " "))
+		}
+		params.WriteString("}\n\n")
+		args = append(args, "params "+pname)
+	}
+	if ope.RequestBody != nil && ope.RequestBody.Value != nil {
+		mt := ope.RequestBody.Value.Content.Get("application/json")
+		if mt == nil || mt.Schema == nil {
+			im.warnf("%v: request body other than application/json is not supported", where)
+		} else {
+			t, _ := im.goType(mt.Schema)
+			args = append(args, "body "+t)
+		}
+	}
+
+	result := ""
+	lines := []string{}
+	for _, code := range sortedKeys(ope.Responses) {
+		res := ope.Responses[code].Value
+		if res == nil {
+			im.warnf("%v: response %v is not supported", where, code)
+			continue
+		}
+		desc := ""
+		if res.Description != nil {
+			desc = *res.Description
+		}
+		lines = append(lines, fmt.Sprintf("%v: %v", code, yamlString(desc)))
+		mt := res.Content.Get("application/json")
+		if code == "default" || mt == nil || mt.Schema == nil {
+			continue
+		}
+		if result != "" || (code != "200" && code != "201") {
+			im.warnf("%v: response %v is written as 200", where, code)
+		}
+		if result == "" {
+			result, _ = im.goType(mt.Schema)
+		}
+	}
+	if ope.Responses["default"] == nil {
+		im.warnf("%v: the default response is added", where)
+		lines = append(lines, "default: unexpected error")
+	}
+	if len(ope.Tags) > 0 {
+		lines = append(lines, "tags: ["+strings.Join(ope.Tags, ", ")+"]")
+	}
+	if ope.Summary != "" {
+		lines = append(lines, "summary: "+yamlString(ope.Summary))
+	}
+
+	doc := strings.TrimSpace(ope.Description)
+	if doc != "" {
+		writeDoc(w, doc)
+		w.WriteString("//\n")
+	}
+	fmt.Fprintf(w, "// (%v %v)\n", method, path)
+	for _, l := range lines {
+		fmt.Fprintf(w, "// %v\n", l)
+	}
+	fmt.Fprintf(w, "%v(%v) %v\n\n", name, strings.Join(args, ", "), result)
+}
+
+func yamlString(s string) string {
+	b, _ := yaml.Marshal(s)
+	return strings.TrimSpace(string(b))
+}
+
+// sortedKeys returns the sorted keys of a map with string keys.
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch o := m.(type) {
+	case openapi3.Schemas:
+		for k := range o {
+			keys = append(keys, k)
+		}
+	case openapi3.Paths:
+		for k := range o {
+			keys = append(keys, k)
+		}
+	case openapi3.Responses:
+		for k := range o {
+			keys = append(keys, k)
+		}
+	case map[string]*openapi3.Operation:
+		for k := range o {
+			keys = append(keys, k)
+		}
+	case map[string]bool:
+		for k := range o {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genimport_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/require"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genimport"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genspec"
+)
+
+const importSrc = `
+openapi: 3.0.0
+info:
+  title: import
+  version: 1.0.0
+paths:
+  /items/{id}:
+    get:
+      operationId: getItem
+      tags: [items]
+      summary: Get an item
+      parameters:
+      - in: path
+        name: id
+        required: true
+        schema:
+          type: string
+      - in: query
+        name: limit
+        required: true
+        description: max count
+        schema:
+          type: integer
+          maximum: 100
+      - in: header
+        name: X-Trace
+        schema:
+          type: string
+      responses:
+        "200":
+          description: the item
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Item'
+components:
+  schemas:
+    Error:
+      type: object
+      required: [message]
+      properties:
+        message:
+          type: string
+    Item:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+          description: the name
+          maxLength: 10
+        kind:
+          $ref: '#/components/schemas/Kind'
+        created:
+          type: string
+          format: date-time
+        shape:
+          $ref: '#/components/schemas/Shape'
+    Kind:
+      type: string
+      enum: [small, large]
+    Shape:
+      oneOf:
+      - $ref: '#/components/schemas/Item'
+      - type: string
+`
+
+func schemaNames(t *openapi3.T) []string {
+	names := []string{}
+	for name := range t.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestImport(t *testing.T) {
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(importSrc))
+	require.NoError(t, err)
+
+	src, warnings, err := genimport.Import(spec, "api")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"GET /items/{id}: header parameter X-Trace is not supported",
+		"GET /items/{id}: the default response is added",
+	}, warnings)
+	require.Contains(t, string(src), "KindSmall Kind = \"small\"")
+	require.Contains(t, string(src), "// max count\n")
+	require.Contains(t, string(src), "GetItem(id string, params GetItemParams) Item")
+
+	out, diags, err := genspec.Generate(context.Background(), &genspec.Config{
+		PackageName: "api",
+		InputFile:   "spec.go",
+		Source:      src,
+	})
+	require.NoError(t, err)
+	require.Empty(t, diags)
+	require.Equal(t, []string{"Error", "GetItemParams", "Item", "Kind", "Shape"}, schemaNames(out))
+
+	item := out.Components.Schemas["Item"].Value
+	require.Equal(t, []string{"name"}, item.Required)
+	require.Equal(t, "the name", item.Properties["name"].Value.Description)
+	require.Equal(t, uint64(10), *item.Properties["name"].Value.MaxLength)
+	require.Equal(t, "date-time", item.Properties["created"].Value.Format)
+	require.Equal(t, "#/components/schemas/Shape", item.Properties["shape"].Ref)
+	require.Len(t, out.Components.Schemas["Shape"].Value.OneOf, 2)
+
+	ope := out.Paths["/items/{id}"].Get
+	require.Equal(t, []string{"items"}, ope.Tags)
+	require.Equal(t, "Get an item", ope.Summary)
+	limit := ope.Parameters.GetByInAndName("query", "limit")
+	require.NotNil(t, limit)
+	require.True(t, limit.Required)
+	require.Equal(t, float64(100), *limit.Schema.Value.Max)
+
+	delete(spec.Components.Schemas, "Error")
+	_, warnings, err = genimport.Import(spec, "api")
+	require.NoError(t, err)
+	require.Contains(t, warnings, "schema Error is not declared in Go, the default responses refer to it")
+}
+
+func TestImportPet(t *testing.T) {
+	spec, err := openapi3.NewLoader().LoadFromFile("../../testdata/pet.yaml")
+	require.NoError(t, err)
+
+	src, warnings, err := genimport.Import(spec, "api")
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	out, diags, err := genspec.Generate(context.Background(), &genspec.Config{
+		PackageName: "api",
+		InputFile:   "spec.go",
+		Source:      src,
+	})
+	require.NoError(t, err)
+	require.Empty(t, diags)
+	require.Equal(t, len(spec.Paths), len(out.Paths))
+	for path, item := range spec.Paths {
+		require.NotNil(t, out.Paths[path], path)
+		require.Equal(t, len(item.Operations()), len(out.Paths[path].Operations()), path)
+	}
+	require.Equal(t, spec.Info.Title, out.Info.Title)
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v2"
+)
+
+// StaleError is the error of Run with Check, when the spec in File differs from the generated one.
+type StaleError struct {
+	File string
+	// Diff is the unified diff of CheckFile.
+	Diff string
+}
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("%v is stale, regenerate it", e.File)
+}
+
+// CheckFile compares t with the spec in file, YAML or JSON, and returns the unified diff, "" if they are the same.
+// The comparison is semantic: the format, the order of the keys and the styles of YAML do not matter.
+// The diff is of both as YAML with sorted keys, and a file that does not exist differs from every spec.
+func CheckFile(t *openapi3.T, file string) (string, error) {
+	var old interface{}
+	data, err := os.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+		data = nil
+	case err != nil:
+		return "", err
+	default:
+		err = yaml.Unmarshal(data, &old)
+		if err != nil {
+			return "", errors.Wrap(err, file)
+		}
+		old, err = canonicalValue(JSONValue(old))
+		if err != nil {
+			return "", errors.Wrap(err, file)
+		}
+	}
+	generated, err := canonicalValue(t)
+	if err != nil {
+		return "", err
+	}
+	if data != nil && reflect.DeepEqual(old, generated) {
+		return "", nil
+	}
+
+	a := ""
+	if data != nil {
+		b, err := yaml.Marshal(old)
+		if err != nil {
+			return "", err
+		}
+		a = string(b)
+	}
+	b, err := yaml.Marshal(generated)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a),
+		B:        difflib.SplitLines(string(b)),
+		FromFile: file,
+		ToFile:   "generated",
+		Context:  3,
+	})
+}
+
+// canonicalValue returns v as decoded from JSON, so the numbers are float64 and the maps map[string]interface{}.
+func canonicalValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var c interface{}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/flynn/json5"
@@ -67,21 +68,150 @@ func (kv KeyValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 }
 
+// ParseTag parses the JSON5 of a tag, ignoring errors. See ParseSchemeTag.
 func ParseTag(tag string) KeyValue {
+	kv, _ := ParseSchemeTag(tag)
+	return kv
+}
+
+// TagError is a syntax error in a tag, Offset is the byte offset in the tag.
+type TagError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *TagError) Error() string {
+	return fmt.Sprintf("invalid tag at %v: %v", e.Offset, e.Msg)
+}
+
+// ParseSchemeTag parses a tag written in JSON5, `{min:4,max:4}`,
+// or in the "scheme" key of a struct tag, `scheme:"min:4,max:4"` (the braces can be omitted).
+func ParseSchemeTag(tag string) (KeyValue, error) {
 	kv := KeyValue{}
+	base := len(tag) - len(strings.TrimLeft(tag, " \t"))
 	tag = strings.TrimSpace(tag)
+	prefix := 0
 	if !strings.HasPrefix(tag, "{") {
-		tag = reflect.StructTag(tag).Get("scheme")
-		tag = strings.TrimSpace(tag)
+		value, ok := reflect.StructTag(tag).Lookup("scheme")
+		if !ok {
+			return kv, nil
+		}
+		base += strings.Index(tag, `scheme:"`) + len(`scheme:"`)
+		tag = strings.TrimSpace(value)
 		if !strings.HasPrefix(tag, "{") {
-			tag = "{" + tag
+			tag = "{" + tag + "}"
+			prefix = 1
 		}
 	}
-	if !strings.HasSuffix(tag, "}") {
-		tag = tag + "}"
+	err := json5.Unmarshal([]byte(tag), &kv)
+	if err != nil {
+		offset := base
+		se, ok := err.(*json5.SyntaxError)
+		if ok && se.Offset > 0 {
+			offset += int(se.Offset) - 1 - prefix
+		}
+		return KeyValue{}, &TagError{Offset: offset, Msg: err.Error()}
 	}
-	_ = json5.Unmarshal([]byte(tag), &kv)
-	return kv
+	return kv, nil
+}
+
+// TagKeywords are the keys of a tag other than "x-" extensions: the shorthands and the keywords of Schema.
+var TagKeywords = []string{
+	"min", "max", "gt", "lt", "gte", "lte", "len", "unique",
+	"type", "format", "title", "description", "enum", "default", "example", "externalDocs",
+	"multipleOf", "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum",
+	"minLength", "maxLength", "pattern",
+	"minItems", "maxItems", "uniqueItems", "items",
+	"minProperties", "maxProperties", "properties", "required", "additionalProperties",
+	"allOf", "anyOf", "oneOf", "not", "discriminator",
+	"nullable", "readOnly", "writeOnly", "deprecated", "allowEmptyValue", "xml",
+}
+
+// CheckTagKeys reports the keys of kv that are not TagKeywords nor "x-" extensions.
+func CheckTagKeys(kv KeyValue) error {
+	errs := []string{}
+	for _, k := range sortedTagKeys(kv) {
+		if strings.HasPrefix(k, "x-") || containsString(TagKeywords, k) {
+			continue
+		}
+		msg := fmt.Sprintf("unknown key %q", k)
+		similar := similarString(TagKeywords, k)
+		if similar != "" {
+			msg += fmt.Sprintf(", did you mean %q?", similar)
+		}
+		errs = append(errs, msg)
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// similarString returns the string of list nearest to s by edit distance, if close enough.
+func similarString(list []string, s string) string {
+	best := ""
+	bestDist := len(s)/4 + 1
+	for _, v := range list {
+		d := editDistance(strings.ToLower(v), strings.ToLower(s))
+		if d <= bestDist {
+			best = v
+			bestDist = d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, minInt(cur[j-1]+1, prev[j-1]+cost))
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// JSONValue converts the maps of a YAML value decoded by yaml.v2 to map[string]interface{}, for encoding/json.
+func JSONValue(obj interface{}) interface{} {
+	switch o := obj.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, v := range o {
+			m[fmt.Sprintf("%v", k)] = JSONValue(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range o {
+			o[i] = JSONValue(v)
+		}
+	}
+	return obj
 }
 
 func Convert(src, dst interface{}) error {
@@ -92,48 +222,104 @@ func Convert(src, dst interface{}) error {
 	return json.Unmarshal(b, dst)
 }
 
-func ExpandTagForScheme(scheme *openapi3.Schema, tag KeyValue) {
+// rangeKeywords are the min/max keywords of each type.
+var rangeKeywords = map[string][2]string{
+	"integer": {"minimum", "maximum"},
+	"number":  {"minimum", "maximum"},
+	"string":  {"minLength", "maxLength"},
+	"array":   {"minItems", "maxItems"},
+	"object":  {"minProperties", "maxProperties"},
+}
+
+// ExpandTagForScheme sets tag to scheme, expanding the shorthands by the type of scheme:
+//
+//	min, gte  minimum, minLength, minItems or minProperties
+//	max, lte  maximum, maxLength, maxItems or maxProperties
+//	gt, lt    exclusiveMinimum/exclusiveMaximum for numbers, +1/-1 of the length for the others
+//	len       both min and max
+//	multipleOf  numbers only
+//	unique    uniqueItems, arrays only
+//
+// Other keys are copied as is. The shorthands that make no sense for the type are reported.
+func ExpandTagForScheme(scheme *openapi3.Schema, tag KeyValue) error {
 	ty := scheme.Type
 	work := KeyValue{}
 	Convert(scheme, &work)
 
-	for k, v := range tag {
+	errs := []string{}
+	invalid := func(k string) {
+		errs = append(errs, fmt.Sprintf("%v is not applicable to type %q", k, ty))
+	}
+	rk, hasRange := rangeKeywords[ty]
+	isNumber := ty == "integer" || ty == "number"
+	for _, k := range sortedTagKeys(tag) {
+		v := tag[k]
 		switch k {
-		case "min":
-			switch ty {
-			case "integer":
-				work["minimum"] = v
-			case "string":
-				work["minLength"] = v
-			case "array":
-				work["minItems"] = v
+		case "min", "gte", "max", "lte", "len":
+			if !hasRange {
+				invalid(k)
+				continue
 			}
-		case "max":
-			switch ty {
-			case "integer":
-				work["maximum"] = v
-			case "string":
-				work["maxLength"] = v
-			case "array":
-				work["maxItems"] = v
+			if k != "max" && k != "lte" {
+				work[rk[0]] = v
 			}
-		case "gt":
-			switch ty {
-			case "integer":
-				work["minimum"] = v
-				work["exclusiveMinimum"] = true
+			if k != "min" && k != "gte" {
+				work[rk[1]] = v
 			}
-		case "lt":
-			switch ty {
-			case "integer":
-				work["maximum"] = v
-				work["exclusiveMaximum"] = true
+		case "gt", "lt":
+			if !hasRange {
+				invalid(k)
+				continue
+			}
+			i := 0
+			exclusive := "exclusiveMinimum"
+			delta := 1.0
+			if k == "lt" {
+				i = 1
+				exclusive = "exclusiveMaximum"
+				delta = -1
+			}
+			if isNumber {
+				work[rk[i]] = v
+				work[exclusive] = true
+				continue
+			}
+			n, ok := v.(float64)
+			if !ok || n != float64(int64(n)) {
+				errs = append(errs, fmt.Sprintf("%v of type %q must be an integer: %v", k, ty, v))
+				continue
+			}
+			work[rk[i]] = n + delta
+		case "multipleOf":
+			if !isNumber {
+				invalid(k)
+				continue
 			}
+			work[k] = v
+		case "unique":
+			if ty != "array" {
+				invalid(k)
+				continue
+			}
+			work["uniqueItems"] = v
 		default:
 			work[k] = v
 		}
 	}
 	Convert(&work, scheme)
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func sortedTagKeys(kv KeyValue) []string {
+	keys := make([]string, 0, len(kv))
+	for k := range kv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func ParseMapSlice(str string) (*yaml.MapSlice, error) {
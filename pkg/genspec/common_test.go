@@ -62,6 +62,7 @@ func TestToScheme(t *testing.T) {
 		schema *openapi3.Schema
 		kv     genspec.KeyValue
 		json   string
+		err    string
 	}
 	cases := []caseT{
 		{
@@ -102,12 +103,12 @@ func TestToScheme(t *testing.T) {
 		{
 			schema: openapi3.NewStringSchema(),
 			kv:     genspec.KeyValue{"lt": float64(4)},
-			json:   `{"type":"string"}`,
+			json:   `{"type":"string","maxLength":3}`,
 		},
 		{
 			schema: openapi3.NewStringSchema(),
 			kv:     genspec.KeyValue{"gt": float64(4)},
-			json:   `{"type":"string"}`,
+			json:   `{"type":"string","minLength":5}`,
 		},
 		{
 			schema: openapi3.NewArraySchema(),
@@ -122,17 +123,60 @@ func TestToScheme(t *testing.T) {
 		{
 			schema: openapi3.NewArraySchema(),
 			kv:     genspec.KeyValue{"lt": float64(4)},
-			json:   `{"type":"array"}`,
+			json:   `{"type":"array","maxItems":3}`,
 		},
 		{
 			schema: openapi3.NewArraySchema(),
 			kv:     genspec.KeyValue{"gt": float64(4)},
-			json:   `{"type":"array"}`,
+			json:   `{"type":"array","minItems":5}`,
+		},
+		{
+			schema: openapi3.NewFloat64Schema(),
+			kv:     genspec.KeyValue{"min": float64(0.5), "lt": float64(4), "multipleOf": float64(0.5)},
+			json:   `{"type":"number","minimum":0.5,"maximum":4,"exclusiveMaximum":true,"multipleOf":0.5}`,
+		},
+		{
+			schema: openapi3.NewStringSchema(),
+			kv:     genspec.KeyValue{"len": float64(4)},
+			json:   `{"type":"string","minLength":4,"maxLength":4}`,
+		},
+		{
+			schema: openapi3.NewArraySchema(),
+			kv:     genspec.KeyValue{"gte": float64(1), "lte": float64(4), "unique": true},
+			json:   `{"type":"array","minItems":1,"maxItems":4,"uniqueItems":true}`,
+		},
+		{
+			schema: openapi3.NewObjectSchema(),
+			kv:     genspec.KeyValue{"min": float64(1)},
+			json:   `{"type":"object","minProperties":1}`,
+		},
+		{
+			schema: openapi3.NewStringSchema(),
+			kv:     genspec.KeyValue{"unique": true, "multipleOf": float64(2)},
+			json:   `{"type":"string"}`,
+			err:    `multipleOf is not applicable to type "string", unique is not applicable to type "string"`,
+		},
+		{
+			schema: openapi3.NewStringSchema(),
+			kv:     genspec.KeyValue{"gt": float64(0.5)},
+			json:   `{"type":"string"}`,
+			err:    `gt of type "string" must be an integer: 0.5`,
+		},
+		{
+			schema: openapi3.NewBoolSchema(),
+			kv:     genspec.KeyValue{"max": float64(1)},
+			json:   `{"type":"boolean"}`,
+			err:    `max is not applicable to type "boolean"`,
 		},
 	}
 	for i, c := range cases {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			genspec.ExpandTagForScheme(c.schema, c.kv)
+			err := genspec.ExpandTagForScheme(c.schema, c.kv)
+			if c.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, c.err)
+			}
 			b, err := json.Marshal(c.schema)
 			require.NoError(t, err)
 			require.JSONEq(t, string(b), c.json)
@@ -147,8 +191,9 @@ func TestParseOpeDoc(t *testing.T) {
 200: pet response
 default: unexpected error
 `
-	opeDoc := genspec.ParseOpeDoc(doc)
-	require.NotNil(t, opeDoc)
+	opeDoc, err := genspec.ParseOpeDoc(doc)
+	require.NoError(t, err)
+	require.Equal(t, opeDoc.Summary, "Description")
 	require.Equal(t, opeDoc.Desc, "Description")
 	require.Equal(t, opeDoc.Method, "GET")
 	require.Equal(t, opeDoc.Path, "/pets")
@@ -156,6 +201,16 @@ default: unexpected error
 		"200":     "pet response",
 		"default": "unexpected error",
 	})
+
+	opeDoc, err = genspec.ParseOpeDoc("Find pets.\nAll of them.\n\n(GET /pets)\nsummary: List pets\n")
+	require.NoError(t, err)
+	require.Equal(t, opeDoc.Summary, "List pets")
+	require.Equal(t, opeDoc.Desc, "Find pets.\nAll of them.")
+
+	_, err = genspec.ParseOpeDoc("Find pets.\n")
+	require.EqualError(t, err, "no (METHOD /path) in the doc")
+	_, err = genspec.ParseOpeDoc("(GET /pets)\n200: [\n")
+	require.Error(t, err)
 }
 
 func Must(err error) {
@@ -336,3 +391,186 @@ a2:
 	require.NoError(t, err)
 	require.JSONEq(t, string(d), `{"a1":{"b1":"c"},"a2":{"b2":"c"}}`)
 }
+
+func TestParseDirectives(t *testing.T) {
+	const doc = `Shape is drawable.
+
+(oneOf kind)
+circle: Circle
+square: Square
+(example ExampleShape)
+`
+	desc, directives, err := genspec.ParseDirectives(doc)
+	require.NoError(t, err)
+	require.Equal(t, "Shape is drawable.", desc)
+	require.Len(t, directives, 2)
+	require.Equal(t, "oneOf", directives[0].Name)
+	require.Equal(t, []string{"kind"}, directives[0].Args)
+	require.Equal(t, genspec.KeyValue{"circle": "Circle", "square": "Square"}, directives[0].KV)
+	require.Equal(t, "example", directives[1].Name)
+	require.Equal(t, []string{"ExampleShape"}, directives[1].Args)
+	require.Empty(t, directives[1].KV)
+
+	_, _, err = genspec.ParseDirectives("(oneOf)\n- a\n")
+	require.Error(t, err)
+
+	// a remark in parentheses is a description.
+	desc, directives, err = genspec.ParseDirectives("Pet is a pet.\n(see the store: for the prices)\n(example ExamplePet)\n")
+	require.NoError(t, err)
+	require.Equal(t, "Pet is a pet.\n(see the store: for the prices)", desc)
+	require.Len(t, directives, 1)
+	require.Equal(t, "example", directives[0].Name)
+	desc, directives, err = genspec.ParseDirectives("(deprecated soon)")
+	require.NoError(t, err)
+	require.Equal(t, "(deprecated soon)", desc)
+	require.Empty(t, directives)
+}
+
+func TestParseValidateTag(t *testing.T) {
+	vt := genspec.ParseValidateTag("required,min=1,max=64,email")
+	require.True(t, vt.Required)
+	require.Equal(t, genspec.KeyValue{"min": float64(1), "max": float64(64), "format": "email"}, vt.KV)
+
+	vt = genspec.ParseValidateTag("omitempty,len=3,unique,dive,oneof=1 2 3")
+	require.False(t, vt.Required)
+	require.True(t, vt.OmitEmpty)
+	require.Equal(t, genspec.KeyValue{"len": float64(3), "unique": true}, vt.KV)
+	require.Equal(t, []string{"1", "2", "3"}, vt.Items.OneOf)
+
+	s := openapi3.NewArraySchema().WithItems(openapi3.NewIntegerSchema())
+	genspec.ExpandValidateTagForScheme(s, vt)
+	require.JSONEq(t, `{
+		"type": "array",
+		"minItems": 3,
+		"maxItems": 3,
+		"uniqueItems": true,
+		"items": {"type": "integer", "enum": [1, 2, 3]}
+	}`, MustJSONStringify(s))
+
+	s = openapi3.NewIntegerSchema()
+	genspec.ExpandValidateTagForScheme(s, genspec.ParseValidateTag("gte=1,lte=10"))
+	require.JSONEq(t, `{"type": "integer", "minimum": 1, "maximum": 10}`, MustJSONStringify(s))
+
+	require.Equal(t, "required", genspec.GetValidateTag(`json:"name" binding:"required"`))
+	require.Equal(t, "min=1", genspec.GetValidateTag(`validate:"min=1" binding:"required"`))
+
+	vt = genspec.ParseValidateTag("oneof='a b' c '',datetime=2006-01-02")
+	require.Equal(t, []string{"a b", "c", ""}, vt.OneOf)
+	require.Equal(t, genspec.KeyValue{"format": "date"}, vt.KV)
+	vt = genspec.ParseValidateTag("datetime=2006-01-02T15:04:05Z07:00")
+	require.Equal(t, genspec.KeyValue{"format": "date-time"}, vt.KV)
+	vt = genspec.ParseValidateTag("datetime=15:04")
+	require.Empty(t, vt.KV)
+}
+
+func TestParseSchemeTag(t *testing.T) {
+	type caseT struct {
+		tag    string
+		offset int
+	}
+	cases := []caseT{
+		{tag: `{min:10,max:10`, offset: 13},
+		{tag: `{pattern:\s}`, offset: 9},
+		{tag: `scheme:"min:10 max:1"`, offset: 15},
+	}
+	for _, c := range cases {
+		t.Run(c.tag, func(t *testing.T) {
+			kv, err := genspec.ParseSchemeTag(c.tag)
+			require.Empty(t, kv)
+			require.IsType(t, &genspec.TagError{}, err)
+			require.Equal(t, c.offset, err.(*genspec.TagError).Offset)
+		})
+	}
+
+	kv, err := genspec.ParseSchemeTag(`json:"name"`)
+	require.NoError(t, err)
+	require.Empty(t, kv)
+}
+
+func TestCheckTagKeys(t *testing.T) {
+	require.NoError(t, genspec.CheckTagKeys(genspec.KeyValue{"min": 1, "maxLength": 2, "x-go-type": "a"}))
+	require.EqualError(t,
+		genspec.CheckTagKeys(genspec.KeyValue{"maxLenght": 1, "foo": 2}),
+		`unknown key "foo", unknown key "maxLenght", did you mean "maxLength"?`)
+}
+
+func TestOverlayApply(t *testing.T) {
+	doc, err := genspec.ParseMapSlice(`
+servers:
+- url: https://a.example.com
+tags:
+- name: a
+- name: b
+- name: c
+paths:
+  /a: {get: {operationId: a}, put: {operationId: b}}
+`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		action string
+		n      int
+		want   string
+		err    string
+	}{
+		{"{target: $.servers, update: {url: https://b.example.com}}", 1,
+			"servers:\n- url: https://a.example.com\n- url: https://b.example.com\n", ""},
+		{"{target: '$.tags[?(@.name != ''b'')]', remove: true}", 2,
+			"tags:\n- name: b\n", ""},
+		{"{target: '$.tags[-1].name', remove: true}", 1,
+			"tags:\n- name: a\n- name: b\n- {}\n", ""},
+		{"{target: $..operationId, remove: true}", 2,
+			"paths:\n  /a:\n    get: {}\n    put: {}\n", ""},
+		{"{target: \"$.paths['/a'].*\", update: {x-a: 1}}", 2,
+			"paths:\n  /a:\n    get:\n      operationId: a\n      x-a: 1\n    put:\n      operationId: b\n      x-a: 1\n", ""},
+		{"{target: '$.tags[?(@.name != ''x==y'')]', remove: true}", 3,
+			"tags: []\n", ""},
+		{"{target: $.nothing, remove: true}", 0, "", ""},
+		{"{target: '$.tags[0].name', update: x}", 0, "", `$["tags"][0]["name"]: cannot update a`},
+		{"{target: $, remove: true}", 0, "", "cannot remove the root"},
+	}
+	for _, tt := range tests {
+		o, err := genspec.ParseOverlay([]byte("overlay: 1.0.0\nactions:\n- " + tt.action))
+		require.NoError(t, err, tt.action)
+		got, n, err := o.Actions[0].Apply(genspec.CopyMapSlice(*doc))
+		if tt.err != "" {
+			require.EqualError(t, err, tt.err, tt.action)
+			continue
+		}
+		require.NoError(t, err, tt.action)
+		require.Equal(t, tt.n, n, tt.action)
+		if tt.want == "" {
+			continue
+		}
+		want, err := genspec.ParseMapSlice(tt.want)
+		require.NoError(t, err)
+		for _, item := range *want {
+			for _, g := range got {
+				if g.Key == item.Key {
+					w, _ := yaml.Marshal(item.Value)
+					v, _ := yaml.Marshal(g.Value)
+					require.Equal(t, string(w), string(v), tt.action)
+				}
+			}
+		}
+	}
+
+	for _, src := range []string{
+		"overlay: 2.0.0\nactions:\n- {target: $, remove: true}",
+		"overlay: 1.0.0\nactions: []",
+		"overlay: 1.0.0\nactions:\n- {target: paths, remove: true}",
+		"overlay: 1.0.0\nactions:\n- {target: '$.paths[?(x)]', remove: true}",
+		"overlay: 1.0.0\nactions:\n- {target: $.paths}",
+	} {
+		_, err := genspec.ParseOverlay([]byte(src))
+		require.Error(t, err, src)
+	}
+}
+
+func TestJSONValue(t *testing.T) {
+	var obj interface{}
+	require.NoError(t, yaml.Unmarshal([]byte("a: {1: [x, {b: true}]}"), &obj))
+	require.Equal(t, map[string]interface{}{
+		"a": map[string]interface{}{"1": []interface{}{"x", map[string]interface{}{"b": true}}},
+	}, genspec.JSONValue(obj))
+}
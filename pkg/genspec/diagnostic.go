@@ -0,0 +1,89 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Diagnostic is a problem found while generating the spec,
+// positioned at the Go declaration that produced it.
+type Diagnostic struct {
+	Pos      token.Position
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	if d.Pos.IsValid() {
+		return fmt.Sprintf("%v: %v: %v", d.Pos, d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%v: %v", d.Severity, d.Message)
+}
+
+func HasError(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func PrintDiagnostics(w io.Writer, diags []Diagnostic) {
+	for _, d := range diags {
+		fmt.Fprintln(w, d.String())
+	}
+}
+
+func (g *Generator) report(pos token.Pos, severity Severity, format string, args ...interface{}) {
+	g.diags = append(g.diags, Diagnostic{
+		Pos:      g.fset.Position(pos),
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (g *Generator) errorf(pos token.Pos, format string, args ...interface{}) {
+	g.report(pos, SeverityError, format, args...)
+}
+
+func (g *Generator) warnf(pos token.Pos, format string, args ...interface{}) {
+	g.report(pos, SeverityWarning, format, args...)
+}
+
+// Diagnostics returns the problems reported by the last Run.
+func (g *Generator) Diagnostics() []Diagnostic {
+	return g.diags
+}
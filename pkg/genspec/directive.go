@@ -0,0 +1,117 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"go/ast"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Directive is a line like "(oneOf kind)" in a doc comment,
+// followed by YAML lines up to the next directive.
+type Directive struct {
+	Name string
+	Args []string
+	KV   KeyValue
+}
+
+// DirectiveNames are the names of the directives, other lines in parentheses are descriptions.
+var DirectiveNames = []string{"oneOf", "anyOf", "enum", "example", "extensions", "readOnly", "writeOnly"}
+
+var DirectivePattern = regexp.MustCompile(`^\((` + strings.Join(DirectiveNames, "|") + `)((?:\s+[^\s()]+)*)\)$`)
+
+// ParseDirectives splits doc into the description and the directives that follow it.
+func ParseDirectives(doc string) (string, []*Directive, error) {
+	lines := strings.Split(doc, "\n")
+	desc := []string{}
+	directives := []*Directive{}
+	body := []string{}
+
+	flush := func() error {
+		if len(directives) == 0 {
+			return nil
+		}
+		d := directives[len(directives)-1]
+		err := yaml.Unmarshal([]byte(strings.Join(body, "\n")), &d.KV)
+		if err != nil {
+			return errors.Wrapf(err, "directive (%v)", d.Name)
+		}
+		body = body[:0]
+		return nil
+	}
+
+	for _, l := range lines {
+		g := DirectivePattern.FindStringSubmatch(strings.TrimSpace(l))
+		if g == nil {
+			if len(directives) == 0 {
+				desc = append(desc, l)
+			} else {
+				body = append(body, l)
+			}
+			continue
+		}
+		err := flush()
+		if err != nil {
+			return "", nil, err
+		}
+		directives = append(directives, &Directive{
+			Name: g[1],
+			Args: strings.Fields(g[2]),
+			KV:   KeyValue{},
+		})
+	}
+	err := flush()
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.TrimSpace(strings.Join(desc, "\n")), directives, nil
+}
+
+func findDirective(directives []*Directive, names ...string) *Directive {
+	for _, d := range directives {
+		for _, n := range names {
+			if d.Name == n {
+				return d
+			}
+		}
+	}
+	return nil
+}
+
+// typeDoc returns the doc comment of ts, or of its declaration when ts is not grouped.
+func typeDoc(gd *ast.GenDecl, ts *ast.TypeSpec) *ast.CommentGroup {
+	if ts.Doc != nil {
+		return ts.Doc
+	}
+	if len(gd.Specs) == 1 {
+		return gd.Doc
+	}
+	return nil
+}
+
+func (g *Generator) parseDirectives(doc *ast.CommentGroup) (string, []*Directive) {
+	if doc == nil {
+		return "", nil
+	}
+	desc, directives, err := ParseDirectives(doc.Text())
+	if err != nil {
+		g.errorf(doc.Pos(), "%v", err)
+	}
+	return desc, directives
+}
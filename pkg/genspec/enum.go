@@ -0,0 +1,241 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+type enumValue struct {
+	Name  string
+	Value interface{}
+	Desc  string
+	// grouped is true for a constant of a const group.
+	grouped bool
+}
+
+// collectEnums collects typed constants, grouped by the name of their type.
+// The constants of a const group are the values of the enum,
+// the others only when the type has the (enum) directive.
+//
+//	type Status string
+//	const (
+//		// available for sale
+//		StatusAvailable Status = "available"
+//		StatusSold      Status = "sold"
+//	)
+//
+//	// (enum)
+//	type Level int
+//	const LevelLow Level = 1
+func (g *Generator) collectEnums(af *ast.File) {
+	for _, d := range af.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.CONST {
+			continue
+		}
+		g.collectEnumsFromConstDecl(gd)
+	}
+}
+
+func (g *Generator) collectEnumsFromConstDecl(gd *ast.GenDecl) {
+	// In a const group, a spec without type and values repeats the previous ones.
+	var typ ast.Expr
+	var values []ast.Expr
+	grouped := gd.Lparen.IsValid()
+	for i, s := range gd.Specs {
+		vs := s.(*ast.ValueSpec)
+		if vs.Type != nil || vs.Values != nil {
+			typ = vs.Type
+			values = vs.Values
+		}
+		name, typed := typ.(*ast.Ident)
+		for j, n := range vs.Names {
+			if n.Name == "_" || j >= len(values) {
+				continue
+			}
+			cv, ok := g.evalConst(values[j], int64(i))
+			if !ok {
+				if typed {
+					g.warnf(n.Pos(), "cannot evaluate constant %v", n.Name)
+				}
+				continue
+			}
+			g.consts[n.Name] = cv
+			if !typed {
+				continue
+			}
+			doc := vs.Doc
+			if !grouped {
+				doc = gd.Doc
+			}
+			desc := strings.TrimSpace(doc.Text())
+			if desc == "" {
+				desc = strings.TrimSpace(vs.Comment.Text())
+			}
+			g.enums[name.Name] = append(g.enums[name.Name], &enumValue{
+				Name:    n.Name,
+				Value:   constantValue(cv),
+				Desc:    desc,
+				grouped: grouped,
+			})
+		}
+	}
+}
+
+// enumValues returns the values of the enum ts, nil if ts is not an enum.
+func (g *Generator) enumValues(ts *ast.TypeSpec, directives []*Directive) []*enumValue {
+	all := findDirective(directives, "enum") != nil
+	values := []*enumValue{}
+	for _, v := range g.enums[ts.Name.Name] {
+		if v.grouped || all {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		if all {
+			g.errorf(ts.Pos(), "enum %v has no constants", ts.Name.Name)
+		}
+		return nil
+	}
+	return values
+}
+
+// isType reports whether expr is a type: a type declared in this file, a predeclared one,
+// or a qualified type mapped by the TypeMappers.
+func (g *Generator) isType(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return g.isType(e.X)
+	case *ast.Ident:
+		if g.types[e.Name] != nil {
+			return true
+		}
+		_, ok := types.Universe.Lookup(e.Name).(*types.TypeName)
+		return ok
+	case *ast.SelectorExpr:
+		x, ok := e.X.(*ast.Ident)
+		return ok && g.mapType(x.Name+"."+e.Sel.Name) != nil
+	}
+	return false
+}
+
+func (g *Generator) evalConst(expr ast.Expr, iota int64) (constant.Value, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		v := constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		return v, v.Kind() != constant.Unknown
+	case *ast.Ident:
+		switch e.Name {
+		case "iota":
+			return constant.MakeInt64(iota), true
+		case "true":
+			return constant.MakeBool(true), true
+		case "false":
+			return constant.MakeBool(false), true
+		}
+		v, ok := g.consts[e.Name]
+		return v, ok
+	case *ast.ParenExpr:
+		return g.evalConst(e.X, iota)
+	case *ast.CallExpr:
+		// conversion: Status(1)
+		if len(e.Args) != 1 || !g.isType(e.Fun) {
+			return nil, false
+		}
+		return g.evalConst(e.Args[0], iota)
+	case *ast.UnaryExpr:
+		x, ok := g.evalConst(e.X, iota)
+		if !ok {
+			return nil, false
+		}
+		return constant.UnaryOp(e.Op, x, 0), true
+	case *ast.BinaryExpr:
+		x, ok := g.evalConst(e.X, iota)
+		if !ok {
+			return nil, false
+		}
+		y, ok := g.evalConst(e.Y, iota)
+		if !ok {
+			return nil, false
+		}
+		switch e.Op {
+		case token.SHL, token.SHR:
+			s, ok := constant.Uint64Val(y)
+			if !ok {
+				return nil, false
+			}
+			return constant.Shift(x, e.Op, uint(s)), true
+		case token.QUO:
+			if x.Kind() == constant.Int && y.Kind() == constant.Int {
+				return constant.BinaryOp(x, token.QUO_ASSIGN, y), true
+			}
+		}
+		v := constant.BinaryOp(x, e.Op, y)
+		return v, v.Kind() != constant.Unknown
+	}
+	return nil, false
+}
+
+func constantValue(v constant.Value) interface{} {
+	switch v.Kind() {
+	case constant.String:
+		return constant.StringVal(v)
+	case constant.Bool:
+		return constant.BoolVal(v)
+	case constant.Int:
+		i, ok := constant.Int64Val(v)
+		if ok {
+			return i
+		}
+	}
+	f, _ := constant.Float64Val(v)
+	return f
+}
+
+func (g *Generator) generateFromEnumType(ts *ast.TypeSpec, values []*enumValue) {
+	ref := g.fromType(ts.Type)
+	if ref.Value == nil {
+		g.errorf(ts.Pos(), "enum %v must have a basic underlying type", ts.Name.Name)
+		return
+	}
+	s := ref.Value
+
+	varnames := []string{}
+	descs := []string{}
+	hasDesc := false
+	for _, v := range values {
+		s.Enum = append(s.Enum, v.Value)
+		varnames = append(varnames, v.Name)
+		descs = append(descs, v.Desc)
+		if v.Desc != "" {
+			hasDesc = true
+		}
+	}
+	if s.Extensions == nil {
+		s.Extensions = map[string]interface{}{}
+	}
+	s.Extensions["x-enum-varnames"] = varnames
+	if hasDesc {
+		s.Extensions["x-enum-descriptions"] = descs
+	}
+
+	g.spec.Components.Schemas[ts.Name.Name] = ref
+	g.setOrigin(pointer("components", "schemas", ts.Name.Name), ts.Pos())
+}
@@ -0,0 +1,344 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+// collectVars collects the variables initialized by a single value, e.g. "var ExamplePet = Pet{...}".
+func (g *Generator) collectVars(af *ast.File) {
+	for _, d := range af.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.VAR {
+			continue
+		}
+		for _, s := range gd.Specs {
+			vs := s.(*ast.ValueSpec)
+			for i, n := range vs.Names {
+				if i < len(vs.Values) {
+					g.vars[n.Name] = vs.Values[i]
+				}
+			}
+		}
+	}
+}
+
+// TypedValue converts the text of an example/default tag to the type of schema.
+// Arrays are comma separated or JSON, objects are JSON.
+func TypedValue(schema *openapi3.Schema, text string) (interface{}, error) {
+	switch schema.Type {
+	case "integer":
+		return strconv.ParseInt(text, 10, 64)
+	case "number":
+		return strconv.ParseFloat(text, 64)
+	case "boolean":
+		return strconv.ParseBool(text)
+	case "array":
+		if strings.HasPrefix(strings.TrimSpace(text), "[") {
+			break
+		}
+		items := &openapi3.Schema{}
+		if schema.Items != nil && schema.Items.Value != nil {
+			items = schema.Items.Value
+		}
+		values := []interface{}{}
+		for _, s := range strings.Split(text, ",") {
+			v, err := TypedValue(items, strings.TrimSpace(s))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case "object":
+	default:
+		return text, nil
+	}
+	var v interface{}
+	err := json.Unmarshal([]byte(text), &v)
+	return v, err
+}
+
+// setExampleFromTag sets the example and default tags to prop, typed by its schema.
+// typed is the schema of the referred type when prop is a $ref.
+func (g *Generator) setExampleFromTag(f *ast.Field, tag string, prop *openapi3.SchemaRef) *openapi3.SchemaRef {
+	st := reflect.StructTag(tag)
+	example, hasExample := st.Lookup("example")
+	def, hasDefault := st.Lookup("default")
+	if !hasExample && !hasDefault {
+		return prop
+	}
+
+	typed := prop.Value
+	if prop.Ref != "" {
+		typed = g.underlyingSchema(f.Type)
+		prop = ref(&openapi3.Schema{
+			AllOf: openapi3.SchemaRefs{prop},
+		})
+	}
+	if hasExample {
+		v, err := TypedValue(typed, example)
+		if err != nil {
+			g.errorf(f.Tag.Pos(), "example: %v", err)
+		} else {
+			prop.Value.Example = v
+		}
+	}
+	if hasDefault {
+		v, err := TypedValue(typed, def)
+		if err != nil {
+			g.errorf(f.Tag.Pos(), "default: %v", err)
+		} else {
+			prop.Value.Default = v
+		}
+	}
+	return prop
+}
+
+// underlyingSchema returns the schema of the underlying type of a named type declared in this file.
+func (g *Generator) underlyingSchema(expr ast.Expr) *openapi3.Schema {
+	seen := map[string]bool{}
+	for {
+		name, ok := embeddedName(expr)
+		if !ok || seen[name] || g.types[name] == nil {
+			break
+		}
+		seen[name] = true
+		expr = g.types[name].Type
+	}
+	r := g.fromType(expr)
+	if r.Value == nil {
+		return &openapi3.Schema{}
+	}
+	return r.Value
+}
+
+// evalExample evaluates the variable name, e.g. "var ExamplePet = Pet{Name: "fluffy"}",
+// into the value of its JSON representation.
+func (g *Generator) evalExample(name string) (interface{}, error) {
+	expr := g.vars[name]
+	if expr == nil {
+		return nil, errors.Errorf("example %v is not declared", name)
+	}
+	return g.evalValue(expr, nil, map[string]bool{name: true})
+}
+
+// evalValue evaluates a literal; typ is the type of the enclosing composite literal, for elided types.
+func (g *Generator) evalValue(expr ast.Expr, typ ast.Expr, visiting map[string]bool) (interface{}, error) {
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		if e.Type != nil {
+			typ = e.Type
+		}
+		return g.evalCompositeLit(e, typ, visiting)
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return g.evalValue(e.X, typ, visiting)
+		}
+	case *ast.ParenExpr:
+		return g.evalValue(e.X, typ, visiting)
+	case *ast.Ident:
+		if e.Name == "nil" {
+			return nil, nil
+		}
+		v, ok := g.vars[e.Name]
+		if ok {
+			if visiting[e.Name] {
+				return nil, errors.Errorf("example %v refers to itself", e.Name)
+			}
+			visiting[e.Name] = true
+			defer delete(visiting, e.Name)
+			return g.evalValue(v, nil, visiting)
+		}
+	case *ast.CallExpr:
+		// conversion: Money(100)
+		if len(e.Args) == 1 && g.isType(e.Fun) {
+			return g.evalValue(e.Args[0], nil, visiting)
+		}
+	}
+	cv, ok := g.evalConst(expr, 0)
+	if !ok {
+		return nil, errors.Errorf("cannot evaluate %v at %v", types.ExprString(expr), g.fset.Position(expr.Pos()))
+	}
+	return constantValue(cv), nil
+}
+
+func (g *Generator) evalCompositeLit(e *ast.CompositeLit, typ ast.Expr, visiting map[string]bool) (interface{}, error) {
+	star, ok := typ.(*ast.StarExpr)
+	if ok {
+		typ = star.X
+	}
+	switch t := typ.(type) {
+	case *ast.ArrayType:
+		values := []interface{}{}
+		for _, elt := range e.Elts {
+			v, err := g.evalValue(elt, t.Elt, visiting)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case *ast.MapType:
+		values := map[string]interface{}{}
+		for _, elt := range e.Elts {
+			kve := elt.(*ast.KeyValueExpr)
+			k, err := g.evalValue(kve.Key, t.Key, visiting)
+			if err != nil {
+				return nil, err
+			}
+			v, err := g.evalValue(kve.Value, t.Value, visiting)
+			if err != nil {
+				return nil, err
+			}
+			values[fmt.Sprintf("%v", k)] = v
+		}
+		return values, nil
+	case *ast.StructType:
+		return g.evalStructLit(e, t, visiting)
+	}
+
+	name, ok := embeddedName(typ)
+	if !ok || g.types[name] == nil {
+		return nil, errors.Errorf("cannot evaluate %v at %v", types.ExprString(e), g.fset.Position(e.Pos()))
+	}
+	return g.evalCompositeLit(e, g.types[name].Type, visiting)
+}
+
+func (g *Generator) evalStructLit(e *ast.CompositeLit, st *ast.StructType, visiting map[string]bool) (interface{}, error) {
+	// Go field name -> field
+	fields := []*ast.Field{}
+	goNames := []string{}
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 {
+			n, _ := embeddedName(f.Type)
+			fields = append(fields, f)
+			goNames = append(goNames, n)
+		}
+		for _, n := range f.Names {
+			fields = append(fields, f)
+			goNames = append(goNames, n.Name)
+		}
+	}
+
+	values := map[string]interface{}{}
+	for i, elt := range e.Elts {
+		var f *ast.Field
+		var goName string
+		value := elt
+		kve, ok := elt.(*ast.KeyValueExpr)
+		if ok {
+			key := kve.Key.(*ast.Ident).Name
+			for j, n := range goNames {
+				if n == key {
+					f = fields[j]
+					goName = n
+				}
+			}
+			value = kve.Value
+		} else if i < len(fields) {
+			f = fields[i]
+			goName = goNames[i]
+		}
+		if f == nil {
+			return nil, errors.Errorf("unknown field at %v", g.fset.Position(elt.Pos()))
+		}
+		name := jsonName(f)
+		if name == "-" {
+			continue
+		}
+
+		v, err := g.evalValue(value, f.Type, visiting)
+		if err != nil {
+			return nil, err
+		}
+		if len(f.Names) == 0 && name == "" {
+			// promote the fields of the embedded struct
+			m, ok := v.(map[string]interface{})
+			if ok {
+				for k, v := range m {
+					if _, ok := values[k]; !ok {
+						values[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = fieldName(goName)
+		}
+		values[name] = v
+	}
+	return values, nil
+}
+
+// setExamples sets the media type examples from "examples" in the operation doc:
+//
+//	examples:
+//	  body: [ExampleNewPet]
+//	  200: [ExamplePet]
+func (g *Generator) setExamples(m *ast.Field, ope *openapi3.Operation, examples interface{}) {
+	kv, ok := examples.(map[string]interface{})
+	if !ok {
+		g.errorf(m.Pos(), "examples must be a map: %v", examples)
+		return
+	}
+	for _, k := range sortedTagKeys(kv) {
+		var content openapi3.Content
+		if k == "body" {
+			if ope.RequestBody != nil && ope.RequestBody.Value != nil {
+				content = ope.RequestBody.Value.Content
+			}
+		} else if ope.Responses[k] != nil && ope.Responses[k].Value != nil {
+			content = ope.Responses[k].Value.Content
+		}
+		if len(content) == 0 {
+			g.errorf(m.Pos(), "examples: %v has no content", k)
+			continue
+		}
+
+		names, ok := kv[k].([]interface{})
+		if !ok {
+			names = []interface{}{kv[k]}
+		}
+		for _, n := range names {
+			name := fmt.Sprintf("%v", n)
+			v, err := g.evalExample(name)
+			if err != nil {
+				g.errorf(m.Pos(), "%v", err)
+				continue
+			}
+			for _, mt := range content {
+				if mt.Examples == nil {
+					mt.Examples = openapi3.Examples{}
+				}
+				mt.Examples[name] = &openapi3.ExampleRef{
+					Value: openapi3.NewExample(v),
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"go/ast"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+func isExtension(key string) bool {
+	return strings.HasPrefix(key, "x-")
+}
+
+// SetExtensions copies the "x-" keys of kv to props, and reports the other keys.
+func SetExtensions(props *openapi3.ExtensionProps, kv KeyValue) error {
+	others := []string{}
+	for _, k := range sortedTagKeys(kv) {
+		if !isExtension(k) {
+			others = append(others, k)
+			continue
+		}
+		if props.Extensions == nil {
+			props.Extensions = map[string]interface{}{}
+		}
+		props.Extensions[k] = kv[k]
+	}
+	if len(others) > 0 {
+		return errors.Errorf("not an extension: %v", strings.Join(others, ", "))
+	}
+	return nil
+}
+
+// extensionsDirective returns the YAML of "(extensions)" in doc:
+//
+//	// (extensions)
+//	// x-go-type: decimal.Decimal
+func (g *Generator) extensionsDirective(docs ...*ast.CommentGroup) KeyValue {
+	kv := KeyValue{}
+	for _, doc := range docs {
+		_, directives := g.parseDirectives(doc)
+		for _, d := range directives {
+			if d.Name == "extensions" {
+				for k, v := range d.KV {
+					kv[k] = v
+				}
+			}
+		}
+	}
+	return kv
+}
+
+// withExtensions sets the extensions to prop, wrapping a $ref by allOf.
+func withExtensions(prop *openapi3.SchemaRef, kv KeyValue) (*openapi3.SchemaRef, error) {
+	if len(kv) == 0 {
+		return prop, nil
+	}
+	if prop.Ref != "" {
+		prop = ref(&openapi3.Schema{
+			AllOf: openapi3.SchemaRefs{prop},
+		})
+	}
+	return prop, SetExtensions(&prop.Value.ExtensionProps, kv)
+}
+
+// setOperationExtensions sets the "x-" keys of the operation doc to the operation,
+// and the extensions of parameters and responses:
+//
+//	x-internal: true
+//	parameters:
+//	  id:
+//	    x-go-name: ID
+//	200:
+//	  description: pet response
+//	  x-cache: 60
+func (g *Generator) setOperationExtensions(m *ast.Field, ope *openapi3.Operation, kv KeyValue) {
+	for _, k := range sortedTagKeys(kv) {
+		v := kv[k]
+		switch {
+		case isExtension(k):
+			SetExtensions(&ope.ExtensionProps, KeyValue{k: v})
+		case k == "parameters":
+			params, ok := v.(map[string]interface{})
+			if !ok {
+				g.errorf(m.Pos(), "parameters must be a map: %v", v)
+				continue
+			}
+			for _, name := range sortedTagKeys(params) {
+				p := ope.Parameters.GetByInAndName("query", name)
+				if p == nil {
+					p = ope.Parameters.GetByInAndName("path", name)
+				}
+				ext, ok := params[name].(map[string]interface{})
+				if p == nil || !ok {
+					g.errorf(m.Pos(), "parameters: %v not found", name)
+					continue
+				}
+				err := SetExtensions(&p.ExtensionProps, ext)
+				if err != nil {
+					g.errorf(m.Pos(), "parameters: %v: %v", name, err)
+				}
+			}
+		case isResCode(k):
+			res, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			ext := KeyValue{}
+			for rk, rv := range res {
+				if rk != "description" {
+					ext[rk] = rv
+				}
+			}
+			err := SetExtensions(&getResponse(ope, k).ExtensionProps, ext)
+			if err != nil {
+				g.errorf(m.Pos(), "%v: %v", k, err)
+			}
+		}
+	}
+}
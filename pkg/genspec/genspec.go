@@ -18,17 +18,17 @@ import (
 	"context"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/parser"
 	"go/token"
 	"io"
-	"log"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
-	"github.com/iancoleman/strcase"
 	"github.com/pkg/errors"
 	"gopkg.in/go-playground/validator.v9"
 	"gopkg.in/yaml.v2"
@@ -39,11 +39,33 @@ type Config struct {
 	PackageName string // `validate:"required"`
 	InputFile   string `validate:"required"`
 	OutputFile  string
-}
-
-func getWriter(out string) (io.Writer, error) {
+	// Source is the source of InputFile, as the src of go/parser.ParseFile. If nil, InputFile is read.
+	Source interface{}
+	// OpenAPI is the version written to openapi, "3.0.0" by default. Only 3.0.x is supported.
+	OpenAPI string
+	// Format is the format of OutputFile, FormatYAML by default or FormatJSON for a ".json" file.
+	Format Format
+	// Check makes Run compare the spec with OutputFile instead of writing it, see CheckFile.
+	Check bool
+	// FailOnInvalid makes Run fail when the generated spec does not validate.
+	FailOnInvalid bool
+	// FlattenEmbedded promotes the fields of embedded structs like encoding/json, instead of allOf.
+	FlattenEmbedded bool
+	// SplitReadWrite generates Input/Output variants of the schemas with readOnly/writeOnly properties.
+	SplitReadWrite bool
+	// StrictTags rejects unknown keys in the tags, e.g. misspelled "maxLenght".
+	StrictTags bool
+	// Overlays are the Overlay files applied to the generated spec, in order.
+	Overlays []string
+	// TypeMappers map Go types to schemas before DefaultTypes, the first non-nil wins.
+	TypeMappers []TypeMapper
+	// Hooks are called while generating, see also Generator.OnSchema, OnField and OnOperation.
+	Hooks Hooks
+}
+
+func getWriter(out string) (io.WriteCloser, error) {
 	if out == "" {
-		return os.Stdout, nil
+		return nopWriteCloser{os.Stdout}, nil
 	}
 	w, err := os.Create(out)
 	if err != nil {
@@ -52,15 +74,42 @@ func getWriter(out string) (io.Writer, error) {
 	return w, nil
 }
 
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
 func ref(val *openapi3.Schema) *openapi3.SchemaRef {
 	return &openapi3.SchemaRef{Value: val}
 }
 
 type Generator struct {
-	config *Config
-	fset   *token.FileSet
-	spec   *openapi3.T
-}
+	config  *Config
+	fset    *token.FileSet
+	spec    *openapi3.T
+	origins map[string]token.Pos
+	// the rules suppressed by "//openapi:ignore", by the JSON pointers
+	ignores map[string][]string
+	diags   []Diagnostic
+	consts  map[string]constant.Value
+	enums   map[string][]*enumValue
+	// method name -> receiver types
+	implementers map[string][]string
+	types        map[string]*ast.TypeSpec
+	typeDecls    []*typeDecl
+	vars         map[string]ast.Expr
+	tagsPos      token.Pos
+	// components of OpenAPISpec, merged after generation
+	specComponents map[string]interface{}
+	specPos        token.Pos
+	mappers        []TypeMapper
+	hooks          Hooks
+}
+
+var openAPIVersion = regexp.MustCompile(`^3\.0\.\d+$`)
 
 func NewGenerator(config *Config) (*Generator, error) {
 	if config.Debug && config.InputFile != "" {
@@ -71,70 +120,225 @@ func NewGenerator(config *Config) (*Generator, error) {
 			return nil, err
 		}
 	}
+	if config.OpenAPI != "" && !openAPIVersion.MatchString(config.OpenAPI) {
+		return nil, errors.Errorf("unsupported OpenAPI version %q, only 3.0.x is supported", config.OpenAPI)
+	}
 	return &Generator{
-		config: config,
-		fset:   token.NewFileSet(),
-		spec:   &openapi3.T{},
+		config:  config,
+		fset:    token.NewFileSet(),
+		spec:    &openapi3.T{},
+		origins: map[string]token.Pos{},
+		ignores: map[string][]string{},
+		consts:  map[string]constant.Value{},
+		enums:   map[string][]*enumValue{},
+
+		implementers: map[string][]string{},
+		types:        map[string]*ast.TypeSpec{},
+		vars:         map[string]ast.Expr{},
+		mappers:      append(append([]TypeMapper{}, config.TypeMappers...), DefaultTypes()),
+		hooks: Hooks{
+			OnSchema:    append([]func(*SchemaHook) error{}, config.Hooks.OnSchema...),
+			OnField:     append([]func(*FieldHook) error{}, config.Hooks.OnField...),
+			OnOperation: append([]func(*OperationHook) error{}, config.Hooks.OnOperation...),
+		},
 	}, nil
 }
 
-func (g *Generator) Run() error {
-	af, err := parser.ParseFile(g.fset, g.config.InputFile, nil, parser.ParseComments)
+// Generate generates the spec of config.InputFile, with the problems found.
+// The error is returned only when the spec cannot be generated, e.g. the source does not parse.
+func Generate(ctx context.Context, config *Config) (*openapi3.T, []Diagnostic, error) {
+	g, err := NewGenerator(config)
 	if err != nil {
-		return errors.Wrap(err, "parser.ParseFile")
+		return nil, nil, err
 	}
-	w, err := getWriter(g.config.OutputFile)
+	t, err := g.Generate(ctx)
+	return t, g.Diagnostics(), err
+}
+
+// Generate generates the spec, a Generator generates only once.
+func (g *Generator) Generate(ctx context.Context) (*openapi3.T, error) {
+	af, err := parser.ParseFile(g.fset, g.config.InputFile, g.config.Source, parser.ParseComments)
 	if err != nil {
-		return err
+		return nil, errors.Wrap(err, "parser.ParseFile")
+	}
+	g.generate(af)
+	g.validate(ctx)
+	if len(g.config.Overlays) > 0 {
+		err = g.applyOverlays(ctx)
+		if err != nil {
+			return nil, err
+		}
 	}
+	return g.spec, nil
+}
 
+func (g *Generator) Run() error {
 	if g.config.Debug {
-		ast.Fprint(w, g.fset, af, nil)
-		return nil
+		af, err := parser.ParseFile(g.fset, g.config.InputFile, g.config.Source, parser.ParseComments)
+		if err != nil {
+			return errors.Wrap(err, "parser.ParseFile")
+		}
+		w, err := getWriter(g.config.OutputFile)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		return ast.Fprint(w, g.fset, af, nil)
 	}
 
-	g.generate(af)
-
-	kv := KeyValue{}
-	err = Convert(g.spec, &kv)
+	t, err := g.Generate(context.Background())
 	if err != nil {
-		return errors.Wrap(err, "Convert Scheme to KeyValue")
-	}
-	// KeyValue -> MapSlice
-	ms := yaml.MapSlice{}
-	keys := []string{"openapi", "info", "paths", "components"}
-	for _, k := range keys {
-		v := kv[k]
-		if v != nil {
-			ms = append(ms, yaml.MapItem{
-				Key:   k,
-				Value: v,
-			})
-			delete(kv, k)
-		}
-	}
-	for k, v := range kv {
-		ms = append(ms, yaml.MapItem{
-			Key:   k,
-			Value: v,
-		})
+		return err
+	}
+	PrintDiagnostics(os.Stderr, g.diags)
+	if g.config.FailOnInvalid && HasError(g.diags) {
+		return errors.New("generated spec is invalid")
 	}
-	bytes, err := yaml.Marshal(&ms)
+
+	if g.config.Check {
+		if g.config.OutputFile == "" {
+			return errors.New("Check needs OutputFile")
+		}
+		diff, err := CheckFile(t, g.config.OutputFile)
+		if err != nil {
+			return err
+		}
+		if diff != "" {
+			return &StaleError{File: g.config.OutputFile, Diff: diff}
+		}
+		return nil
+	}
+
+	format := g.config.Format
+	if format == "" {
+		format = FormatOf(g.config.OutputFile)
+	}
+	w, err := getWriter(g.config.OutputFile)
 	if err != nil {
-		return errors.Wrap(err, "yaml.Marshal")
+		return err
 	}
-	_, err = w.Write(bytes)
+	err = WriteTo(w, t, format)
+	cerr := w.Close()
 	if err != nil {
-		return errors.Wrap(err, "w.Write")
+		return err
 	}
-	return nil
+	return errors.Wrap(cerr, "Close")
+}
+
+type typeDecl struct {
+	gd *ast.GenDecl
+	ts *ast.TypeSpec
 }
 
 func (g *Generator) generate(af *ast.File) {
 	g.spec.OpenAPI = "3.0.0"
+	if g.config.OpenAPI != "" {
+		g.spec.OpenAPI = g.config.OpenAPI
+	}
 	g.spec.Components.Schemas = openapi3.Schemas{}
 	g.spec.Paths = openapi3.Paths{}
+	g.setIgnores("#", af.Doc)
+
+	// 1st pass: collect declarations, the order in the file does not matter.
+	g.collectTypes(af)
+	g.collectEnums(af)
+	g.collectImplementers(af)
+	g.collectVars(af)
+
+	// 2nd pass: values, schemas and then operations.
+	g.generateValues(af)
+	operations := []*ast.TypeSpec{}
+	for _, decl := range g.typeDecls {
+		ts := decl.ts
+		_, directives := g.parseDirectives(typeDoc(decl.gd, ts))
+		g.setIgnores(pointer("components", "schemas", ts.Name.Name), typeDoc(decl.gd, ts))
+		switch i := ts.Type.(type) {
+		case *ast.StructType:
+			g.generateFromStructType(ts, i)
+		case *ast.InterfaceType:
+			d := findDirective(directives, "oneOf", "anyOf")
+			if d != nil || isUnion(i) {
+				g.generateUnion(ts, i, d)
+			} else {
+				operations = append(operations, ts)
+			}
+		default:
+			values := g.enumValues(ts, directives)
+			if values != nil {
+				g.generateFromEnumType(ts, values)
+			} else {
+				g.generateFromNamedType(ts)
+			}
+		}
+		g.applyTypeDirectives(ts, directives)
+	}
+	for _, ts := range operations {
+		g.generateFromInterfaceType(ts, ts.Type.(*ast.InterfaceType))
+	}
+	g.mergeComponents()
+	if g.config.SplitReadWrite {
+		g.splitReadWrite()
+	}
+	// after the split, the hooks see the variants too.
+	g.schemaHooks()
+	g.checkCycles()
+}
 
+// applyTypeDirectives applies the directives in the doc of a type to its schema:
+//
+//	(example ExamplePet)
+//	(extensions)
+//	x-go-type: decimal.Decimal
+func (g *Generator) applyTypeDirectives(ts *ast.TypeSpec, directives []*Directive) {
+	schema := g.spec.Components.Schemas[ts.Name.Name]
+	if schema == nil {
+		return
+	}
+	for _, d := range directives {
+		switch d.Name {
+		case "example":
+			if len(d.Args) != 1 || schema.Value == nil {
+				g.errorf(ts.Pos(), "invalid (example): %v", d.Args)
+				continue
+			}
+			v, err := g.evalExample(d.Args[0])
+			if err != nil {
+				g.errorf(ts.Pos(), "%v", err)
+				continue
+			}
+			schema.Value.Example = v
+		case "extensions":
+			wrapped, err := withExtensions(schema, d.KV)
+			if err != nil {
+				g.errorf(ts.Pos(), "(extensions): %v", err)
+			}
+			g.spec.Components.Schemas[ts.Name.Name] = wrapped
+			schema = wrapped
+		}
+	}
+}
+
+func (g *Generator) collectTypes(af *ast.File) {
+	for _, d := range af.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, s := range gd.Specs {
+			ts := s.(*ast.TypeSpec)
+			if g.types[ts.Name.Name] != nil {
+				g.errorf(ts.Pos(), "%v redeclared", ts.Name.Name)
+				continue
+			}
+			g.types[ts.Name.Name] = ts
+			g.typeDecls = append(g.typeDecls, &typeDecl{gd: gd, ts: ts})
+		}
+	}
+}
+
+// generateValues generates from const/var declarations, OpenAPISpec first as it replaces the spec.
+func (g *Generator) generateValues(af *ast.File) {
+	values := []*ast.ValueSpec{}
 	for _, d := range af.Decls {
 		gd, ok := d.(*ast.GenDecl)
 		if !ok {
@@ -142,30 +346,34 @@ func (g *Generator) generate(af *ast.File) {
 		}
 		for _, s := range gd.Specs {
 			vs, ok := s.(*ast.ValueSpec)
-			if ok {
-				g.generateFromValueSpec(vs)
+			if !ok {
+				continue
 			}
-			ts, ok := s.(*ast.TypeSpec)
-			if ok {
-				switch i := ts.Type.(type) {
-				case *ast.StructType:
-					g.generateFromStructType(ts, i)
-				case *ast.InterfaceType:
-					g.generateFromInterfaceType(ts, i)
-				}
+			if len(vs.Names) == 1 && vs.Names[0].Name == "OpenAPISpec" {
+				values = append([]*ast.ValueSpec{vs}, values...)
+			} else {
+				values = append(values, vs)
 			}
 		}
 	}
+	for _, vs := range values {
+		g.generateFromValueSpec(vs)
+	}
 }
 
 func (g *Generator) generateFromValueSpec(vs *ast.ValueSpec) {
 	if vs.Names == nil || len(vs.Names) != 1 {
 		return
 	}
-	log.Println(vs.Names[0].Name)
 	switch vs.Names[0].Name {
 	case "OpenAPISpec":
 		g.fromOpenAPISpec(vs)
+	case "Servers":
+		g.fromServers(vs)
+	case "Tags":
+		g.fromTags(vs)
+	case "ExternalDocs":
+		g.fromExternalDocs(vs)
 	case "Auth":
 		g.fromAuth(vs)
 	}
@@ -187,13 +395,23 @@ func getBasicLitValue(vs *ast.ValueSpec) (string, bool) {
 }
 
 func (g *Generator) fromOpenAPISpec(vs *ast.ValueSpec) {
-	spec, ok := getBasicLitValue(vs)
-	if !ok {
-		log.Panic("Invalid OpenAPISpec", vs.Values)
+	var obj map[string]interface{}
+	if !g.unmarshalValue(vs, &obj) {
+		return
 	}
-	t, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	t := &openapi3.T{}
+	err := Convert(obj, t)
 	if err != nil {
-		log.Panic("Invalid OpenAPISpec", spec)
+		g.errorf(vs.Pos(), "invalid OpenAPISpec: %v", err)
+		return
+	}
+	if len(t.Paths) > 0 {
+		g.warnf(vs.Pos(), "paths of OpenAPISpec are ignored, they are generated from the interfaces")
+	}
+	components, ok := obj["components"].(map[string]interface{})
+	if ok {
+		g.specComponents = components
+		g.specPos = vs.Pos()
 	}
 
 	t.OpenAPI = g.spec.OpenAPI
@@ -222,16 +440,23 @@ func CSVMergeLines(src []string) []string {
 func (g *Generator) fromAuth(vs *ast.ValueSpec) {
 	auth, ok := getBasicLitValue(vs)
 	if !ok {
-		log.Panic("Invalid fromAuth", vs.Values)
+		g.errorf(vs.Pos(), "Auth must be a string literal")
+		return
 	}
 	ss, err := GenerateSecuritySchemes(auth)
 	if err != nil {
-		log.Panic(err)
+		g.errorf(vs.Pos(), "Auth: %v", err)
+		return
 	}
 
+	names := make([]string, 0, len(*ss))
+	for k := range *ss {
+		names = append(names, k)
+	}
+	sort.Strings(names)
 	secs := openapi3.SecurityRequirements{}
-	for k, v := range *ss {
-		flows := v.Value.Flows
+	for _, k := range names {
+		flows := (*ss)[k].Value.Flows
 
 		sec := openapi3.SecurityRequirement{}
 		sec[k] = []string{}
@@ -251,17 +476,25 @@ func (g *Generator) fromAuth(vs *ast.ValueSpec) {
 				for scope := range scopes {
 					sec[k] = append(sec[k], scope)
 				}
+				sort.Strings(sec[k])
 			}
 		}
 		secs = append(secs, sec)
 	}
+	if len(g.spec.Security) > 0 {
+		g.warnf(vs.Pos(), "Auth overrides the security of OpenAPISpec")
+	}
 	g.spec.Components.SecuritySchemes = *ss
 	g.spec.Security = secs
+	g.setOrigin(pointer("components", "securitySchemes"), vs.Pos())
 }
 
 func GenerateSecuritySchemes(text string) (*openapi3.SecuritySchemes, error) {
 	var obj interface{}
-	yaml.Unmarshal([]byte(text), &obj)
+	err := yaml.Unmarshal([]byte(text), &obj)
+	if err != nil {
+		return nil, err
+	}
 
 	str, ok := obj.(string)
 	schemes := openapi3.SecuritySchemes{}
@@ -290,115 +523,77 @@ func GenerateSecuritySchemes(text string) (*openapi3.SecuritySchemes, error) {
 	// }
 
 	hash, ok := obj.(map[interface{}]interface{})
-	if ok {
-		for k, v := range hash {
-			name := fmt.Sprintf("%v", k)
-			_, schema, err := GenerateSecuritySchemeInterface(v)
-			if err != nil {
-				return nil, errors.Errorf("GenerateSecuritySchemes:UnkownType %v:%v:%v", obj, name, err.Error())
-			}
-			schemes[name] = &openapi3.SecuritySchemeRef{Value: schema}
+	if !ok {
+		return nil, errors.Errorf("must be a string or a map: %v", obj)
+	}
+	for k, v := range hash {
+		name := fmt.Sprintf("%v", k)
+		_, schema, err := GenerateSecuritySchemeInterface(v)
+		if err != nil {
+			return nil, errors.Errorf("GenerateSecuritySchemes:UnkownType %v:%v:%v", obj, name, err.Error())
 		}
+		schemes[name] = &openapi3.SecuritySchemeRef{Value: schema}
 	}
 
 	return &schemes, nil
 }
 
 func (g *Generator) generateFromStructType(ts *ast.TypeSpec, s *ast.StructType) {
-	if ts.Name == nil {
-		log.Panicf("%+v", ts)
-	}
-
 	schemas := g.spec.Components.Schemas
 	schemas[ts.Name.Name] = g.fromStruct(s)
+	g.setOrigin(pointer("components", "schemas", ts.Name.Name), ts.Pos())
 }
 
-func (g *Generator) fromStruct(s *ast.StructType) *openapi3.SchemaRef {
-	parentRef := ""
-	required := []string{}
-	properties := openapi3.Schemas{}
-
-	for _, f := range s.Fields.List {
-		if len(f.Names) == 0 {
-			i, ok := f.Type.(*ast.Ident)
-			if !ok {
-				log.Panic("Cannot get indent")
-			}
-			if parentRef != "" {
-				log.Panic("Already exist parent")
-			}
-			parentRef = "#/components/schemas/" + i.Name
-		} else {
-			name := strcase.ToLowerCamel(f.Names[0].Name)
-			prop := fromType(f.Type)
-			required = append(required, name)
-			if f.Tag != nil {
-				tag, err := strconv.Unquote(f.Tag.Value)
-				if err == nil {
-					setSchemaFromTag(prop, tag)
-				}
-			}
-			properties[name] = prop
-		}
-	}
-
-	schema := &openapi3.Schema{
-		Type:       "object",
-		Properties: properties,
-		Required:   required,
-	}
-
-	if parentRef == "" {
-		return ref(schema)
-	}
-
-	return ref(&openapi3.Schema{
-		AllOf: openapi3.SchemaRefs{
-			{Ref: parentRef},
-			{Value: schema},
-		},
-	})
-}
-
-func setSchemaFromTag(ref *openapi3.SchemaRef, tag string) {
-	if ref == nil || ref.Value == nil {
-		return
-	}
-	kv := ParseTag(tag)
-	if len(kv) == 0 {
-		return
-	}
-	ExpandTagForScheme(ref.Value, kv)
+// generateFromNamedType generates the underlying schema of a named type or alias,
+// e.g. "type Tags []string" or "type Money int64".
+func (g *Generator) generateFromNamedType(ts *ast.TypeSpec) {
+	g.spec.Components.Schemas[ts.Name.Name] = g.fromType(ts.Type)
+	g.setOrigin(pointer("components", "schemas", ts.Name.Name), ts.Pos())
 }
 
-func fromType(expr ast.Expr) *openapi3.SchemaRef {
+func (g *Generator) fromType(expr ast.Expr) *openapi3.SchemaRef {
 	switch i := expr.(type) {
 	case *ast.Ident:
-		return fromIdent(i)
+		return g.fromIdent(i)
 	case *ast.ArrayType:
-		return fromArrayType(i)
+		return g.fromArrayType(i)
+	case *ast.StarExpr:
+		return g.fromType(i.X)
+	case *ast.StructType:
+		return g.fromStruct(i)
+	case *ast.MapType:
+		return g.fromMapType(i)
+	case *ast.SelectorExpr:
+		return g.fromSelectorExpr(i)
+	case *ast.InterfaceType:
+		// any value
+		return ref(&openapi3.Schema{})
 	default:
-		log.Panicf("Unkown type.: %v", expr)
-		return nil
+		g.errorf(expr.Pos(), "Unkown type.: %T", expr)
+		return ref(&openapi3.Schema{})
 	}
 }
 
-func fromArrayType(i *ast.ArrayType) *openapi3.SchemaRef {
+func (g *Generator) fromArrayType(i *ast.ArrayType) *openapi3.SchemaRef {
+	elt, ok := i.Elt.(*ast.Ident)
+	if ok && (elt.Name == "byte" || elt.Name == "uint8") && i.Len == nil {
+		return ref(&openapi3.Schema{Type: "string", Format: "byte"})
+	}
 	return ref(&openapi3.Schema{
 		Type:  "array",
-		Items: fromType(i.Elt),
+		Items: g.fromType(i.Elt),
 	})
 }
 
-var DefaultIdentScheme = map[string]*openapi3.Schema{
-	"int":    {Type: "integer"},
-	"int32":  {Type: "integer", Format: "int32"},
-	"int64":  {Type: "integer", Format: "int64"},
-	"string": {Type: "string"},
+func (g *Generator) fromMapType(i *ast.MapType) *openapi3.SchemaRef {
+	return ref(&openapi3.Schema{
+		Type:                 "object",
+		AdditionalProperties: g.fromType(i.Value),
+	})
 }
 
-func fromIdent(i *ast.Ident) *openapi3.SchemaRef {
-	s := DefaultIdentScheme[i.Name]
+func (g *Generator) fromIdent(i *ast.Ident) *openapi3.SchemaRef {
+	s := g.mapType(i.Name)
 	if s != nil {
 		return ref(s)
 	}
@@ -408,33 +603,51 @@ func fromIdent(i *ast.Ident) *openapi3.SchemaRef {
 	}
 }
 
-func (g *Generator) lookupSchema(expr ast.Expr) *openapi3.SchemaRef {
-	i, ok := expr.(*ast.Ident)
-	if !ok {
-		return nil
+// fromSelectorExpr maps qualified types by the TypeMappers, others refer to the schema of the same name.
+func (g *Generator) fromSelectorExpr(i *ast.SelectorExpr) *openapi3.SchemaRef {
+	x, ok := i.X.(*ast.Ident)
+	if ok {
+		s := g.mapType(x.Name + "." + i.Sel.Name)
+		if s != nil {
+			return ref(s)
+		}
 	}
-	return g.spec.Components.Schemas[i.Name]
+	return g.fromIdent(i.Sel)
 }
 
 func (g *Generator) appendQuery(ope *openapi3.Operation, expr ast.Expr) {
-	ref := g.lookupSchema(expr)
-	if ref == nil || ref.Value == nil || ref.Value.Type != "object" {
-		log.Panicf("Unexpected expr: %v", ref)
+	name, _ := embeddedName(expr)
+	ts := g.types[name]
+	if ts == nil {
+		g.errorf(expr.Pos(), "params must be a struct declared in this file")
+		return
+	}
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		g.errorf(expr.Pos(), "params must be a struct: %v", name)
+		return
 	}
 
-	for n, p := range ref.Value.Properties {
+	fields, _ := g.structFields(st, true, map[string]bool{name: true})
+	for _, f := range dominantFields(fields) {
+		var example interface{}
+		if f.schema.Value != nil {
+			example = f.schema.Value.Example
+		}
 		ope.Parameters = append(ope.Parameters, &openapi3.ParameterRef{
 			Value: &openapi3.Parameter{
-				Name:   n,
-				In:     "query",
-				Schema: p,
+				Name:     f.name,
+				In:       "query",
+				Required: f.validated && f.required,
+				Schema:   f.schema,
+				Example:  example,
 			},
 		})
 	}
 }
 
 func (g *Generator) appendBody(ope *openapi3.Operation, expr ast.Expr) {
-	ref := fromType(expr)
+	ref := g.fromType(expr)
 	ope.RequestBody = &openapi3.RequestBodyRef{
 		Value: &openapi3.RequestBody{
 			Required: true,
@@ -453,7 +666,7 @@ func (g *Generator) appendPath(ope *openapi3.Operation, name string, expr ast.Ex
 			Name:     name,
 			In:       "path",
 			Required: true,
-			Schema:   fromType(expr),
+			Schema:   g.fromType(expr),
 		},
 	})
 }
@@ -484,7 +697,7 @@ func (g *Generator) setResponse(ope *openapi3.Operation, name string, ref *opena
 }
 
 func (g *Generator) appendResponse(ope *openapi3.Operation, r *ast.Field) {
-	g.setResponse(ope, "200", fromType(r.Type))
+	g.setResponse(ope, "200", g.fromType(r.Type))
 }
 
 func (g *Generator) setOperation(path string, method string, ope *openapi3.Operation) {
@@ -506,15 +719,18 @@ func isResCode(name string) bool {
 }
 
 type OpeDoc struct {
-	Desc   string
-	Method string
-	Path   string
-	KV     KeyValue
+	// Summary is the summary key of the doc, or else the first line of Desc.
+	Summary string
+	Desc    string
+	Method  string
+	Path    string
+	KV      KeyValue
 }
 
 var PathPattern = regexp.MustCompile("\\(([A-Z]+) (/.+)\\)")
 
-func ParseOpeDoc(doc string) *OpeDoc {
+// ParseOpeDoc parses the doc of an operation, the description, "(METHOD /path)" and the YAML that follows.
+func ParseOpeDoc(doc string) (*OpeDoc, error) {
 	lines := strings.Split(doc, "\n")
 	for i, l := range lines {
 		g := PathPattern.FindStringSubmatch(l)
@@ -524,29 +740,40 @@ func ParseOpeDoc(doc string) *OpeDoc {
 			kv := KeyValue{}
 			err := yaml.Unmarshal([]byte(rest), &kv)
 			if err != nil {
-				log.Panic("ParseOpeDoc:", err)
+				return nil, errors.Wrapf(err, "(%v %v)", g[1], g[2])
 			}
-			return &OpeDoc{
-				Desc:   strings.TrimSpace(desc),
-				Method: g[1],
-				Path:   g[2],
-				KV:     kv,
+			desc = strings.TrimSpace(desc)
+			summary, ok := kv["summary"].(string)
+			if !ok {
+				summary = strings.TrimSpace(strings.SplitN(desc, "\n", 2)[0])
 			}
+			return &OpeDoc{
+				Summary: summary,
+				Desc:    desc,
+				Method:  g[1],
+				Path:    g[2],
+				KV:      kv,
+			}, nil
 		}
 	}
-	return nil
+	return nil, errors.New("no (METHOD /path) in the doc")
 }
 
 func (g *Generator) generateFromInterfaceType(ts *ast.TypeSpec, i *ast.InterfaceType) {
 	for _, m := range i.Methods.List {
+		ft, ok := m.Type.(*ast.FuncType)
+		if !ok || len(m.Names) == 0 {
+			g.errorf(m.Pos(), "%v: embedded interfaces are not supported", ts.Name.Name)
+			continue
+		}
 		name := m.Names[0].Name
-		opeDoc := ParseOpeDoc(m.Doc.Text())
-		if opeDoc == nil {
-			log.Panicf("Not found pathinfo: %v\n", name)
+		opeDoc, err := ParseOpeDoc(m.Doc.Text())
+		if err != nil {
+			g.errorf(m.Pos(), "%v: %v", name, err)
+			continue
 		}
-		ft, ok := m.Type.(*ast.FuncType)
-		if !ok {
-			log.Panicf("Not FuncType: %v\n", name)
+		if !g.checkParams(name, ft) {
+			continue
 		}
 
 		ope := &openapi3.Operation{
@@ -555,11 +782,20 @@ func (g *Generator) generateFromInterfaceType(ts *ast.TypeSpec, i *ast.Interface
 			Responses:   openapi3.Responses{},
 		}
 		g.setOperation(opeDoc.Path, opeDoc.Method, ope)
+		g.setOrigin(pointer("paths", opeDoc.Path, strings.ToLower(opeDoc.Method)), m.Pos())
+		g.setIgnores(pointer("paths", opeDoc.Path, strings.ToLower(opeDoc.Method)), m.Doc, m.Comment)
 		if opeDoc.Desc != "" {
 			ope.Description = opeDoc.Desc
 		}
+		if _, ok := opeDoc.KV["summary"]; ok {
+			ope.Summary = opeDoc.Summary
+		}
 		for k, v := range opeDoc.KV {
 			if isResCode(k) {
+				res, ok := v.(map[string]interface{})
+				if ok {
+					v = res["description"]
+				}
 				g.setResponseDesc(ope, k, fmt.Sprintf("%v", v))
 			}
 		}
@@ -584,27 +820,61 @@ func (g *Generator) generateFromInterfaceType(ts *ast.TypeSpec, i *ast.Interface
 				g.appendResponse(ope, r)
 			}
 		}
+		tags, ok := opeDoc.KV["tags"]
+		if ok {
+			g.setOperationTags(m, ope, tags)
+		}
+		examples, ok := opeDoc.KV["examples"]
+		if ok {
+			g.setExamples(m, ope, examples)
+		}
+		g.setOperationExtensions(m, ope, opeDoc.KV)
+		g.operationHooks(m, opeDoc.Method, opeDoc.Path, ope)
+	}
+}
+
+// checkParams reports the parameters of the method without the names.
+func (g *Generator) checkParams(name string, ft *ast.FuncType) bool {
+	for _, p := range ft.Params.List {
+		if len(p.Names) == 0 {
+			g.errorf(p.Pos(), "%v: the parameters must be named", name)
+			return false
+		}
 	}
+	return true
 }
 
 func GenerateOAuth2Scheme(m map[interface{}]interface{}) (string, *openapi3.SecurityScheme, error) {
 	flows := KeyValue{}
 	flow := openapi3.OAuthFlow{}
 	for k, v := range m {
-		key := k.(string)
+		key, ok := k.(string)
+		if !ok {
+			return "", nil, errors.Errorf("oauth2: invalid key %v", k)
+		}
+		if key == "scopes" {
+			b, err := yaml.Marshal(v)
+			if err == nil {
+				err = yaml.Unmarshal(b, &flow.Scopes)
+			}
+			if err != nil {
+				return "", nil, errors.Wrap(err, "oauth2: scopes")
+			}
+			continue
+		}
+		value, ok := v.(string)
+		if !ok {
+			return "", nil, errors.Errorf("oauth2: %v must be a string: %v", key, v)
+		}
 		switch key {
 		case "flow":
-			flows[v.(string)] = &flow
+			flows[value] = &flow
 		case "authUrl", "authorizationUrl":
-			flow.AuthorizationURL = v.(string)
+			flow.AuthorizationURL = value
 		case "tokenUrl":
-			flow.TokenURL = v.(string)
+			flow.TokenURL = value
 		case "refreshUrl":
-			flow.RefreshURL = v.(string)
-		case "scopes":
-			b, _ := yaml.Marshal(v)
-			yaml.Unmarshal(b, &flow.Scopes)
-			// flow.Scopes = v.(map[string]string)
+			flow.RefreshURL = value
 		}
 	}
 	b, err := yaml.Marshal(flows)
@@ -651,6 +921,10 @@ func GenerateSecurityScheme(text string) (string, *openapi3.SecurityScheme, erro
 	TrimSpaceAll(cells)
 
 	kind := cells[0]
+	args := map[string]int{"apiKey": 3, "cookie": 2, "query": 2, "header": 2, "oidc": 2, "openIdConnect": 2}
+	if len(cells) < args[kind] {
+		return "", nil, errors.Errorf("%v must have %d values separated by commas: %v", kind, args[kind], text)
+	}
 	switch kind {
 	case "basic", "bearer":
 		s := openapi3.NewSecurityScheme().WithType("http").WithScheme(kind)
@@ -671,5 +945,5 @@ func GenerateSecurityScheme(text string) (string, *openapi3.SecurityScheme, erro
 		s := openapi3.NewOIDCSecurityScheme(cells[1])
 		return kind, s, nil
 	}
-	return "", nil, err
+	return "", nil, errors.Errorf("unknown security scheme %q", kind)
 }
@@ -0,0 +1,1559 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/iancoleman/strcase"
+	"github.com/stretchr/testify/require"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genspec"
+	"gopkg.in/yaml.v2"
+)
+
+type generated struct {
+	kv    genspec.KeyValue
+	diags []genspec.Diagnostic
+	err   error
+}
+
+func generate(t *testing.T, src string, config genspec.Config) *generated {
+	dir := t.TempDir()
+	config.InputFile = filepath.Join(dir, "spec.go")
+	config.OutputFile = filepath.Join(dir, "openapi.yaml")
+	Must(os.WriteFile(config.InputFile, []byte(src), 0644))
+
+	g, err := genspec.NewGenerator(&config)
+	require.NoError(t, err)
+	res := &generated{kv: genspec.KeyValue{}}
+	res.err = g.Run()
+	res.diags = g.Diagnostics()
+	if res.err != nil {
+		return res
+	}
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var obj interface{}
+	require.NoError(t, yaml.Unmarshal(data, &obj))
+	res.kv = normalize(obj).(map[string]interface{})
+	return res
+}
+
+// normalize converts map[interface{}]interface{} from yaml.v2 into map[string]interface{}.
+func normalize(obj interface{}) interface{} {
+	switch o := obj.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, v := range o {
+			m[fmt.Sprintf("%v", k)] = normalize(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range o {
+			o[i] = normalize(v)
+		}
+	}
+	return obj
+}
+
+func (g *generated) schema(name string) interface{} {
+	components := g.kv["components"].(map[string]interface{})
+	return components["schemas"].(map[string]interface{})[name]
+}
+
+func TestValidate(t *testing.T) {
+	const src = `package api
+
+type Error struct {
+	Message string
+}
+
+type Pet struct {
+	Name  string
+	Owner Person
+}
+
+type Interface interface {
+	// (GET /pets)
+	// 200: pet response
+	FindPets() []Pet
+}
+`
+	res := generate(t, src, genspec.Config{FailOnInvalid: true})
+	require.Error(t, res.err)
+	require.Len(t, res.diags, 1)
+	require.Equal(t, 7, res.diags[0].Pos.Line)
+	require.Equal(t, "unresolved ref #/components/schemas/Person", res.diags[0].Message)
+
+	res = generate(t, src, genspec.Config{})
+	require.NoError(t, res.err)
+	require.NotNil(t, res.schema("Pet"))
+}
+
+func TestValidateIdentSchemeNotShared(t *testing.T) {
+	const src = `package api
+
+type Error struct {
+	Message string
+}
+
+type Code string
+
+type Pet struct {
+	Name  string ` + "`{format: \"[a-z]\"}`" + `
+	Label string
+	Code  Code ` + "`{pattern: \"^[a-z]+$\"}`" + `
+	Alias Code
+}
+`
+	res := generate(t, src, genspec.Config{})
+	require.Len(t, res.diags, 1)
+	require.Equal(t, 9, res.diags[0].Pos.Line)
+
+	// the format of name is not copied to the other string field, nor the pattern of code to Code.
+	props := res.schema("Pet").(map[string]interface{})["properties"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{"type": "string", "format": "[a-z]"}, props["name"])
+	require.Equal(t, map[string]interface{}{"type": "string"}, props["label"])
+	require.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/Code"}, props["alias"])
+	require.Equal(t, map[string]interface{}{"type": "string"}, res.schema("Code"))
+}
+
+func TestEnum(t *testing.T) {
+	const src = `package api
+
+type Status string
+
+const (
+	// available for sale
+	StatusAvailable Status = "available"
+	StatusSold      Status = "sold" // sold out
+)
+
+// not a value of the enum
+const DefaultStatus Status = StatusSold
+
+type Level int
+
+const (
+	LevelLow Level = iota + 1
+	LevelMid
+	_
+	LevelHigh
+	LevelMax = LevelHigh << 2
+	LevelLen Level = len("abcd")
+)
+
+// (enum)
+type Color string
+
+// red color
+const ColorRed Color = "red"
+
+const ColorBlue Color = "blue"
+
+type Pet struct {
+	Status Status
+	Level  Level
+	Color  Color
+}
+` + openAPISpecSrc
+	res := generate(t, src, genspec.Config{})
+	require.NoError(t, res.err)
+	require.Len(t, res.diags, 1)
+	require.Contains(t, res.diags[0].Message, "cannot evaluate constant LevelLen")
+	require.Equal(t, map[string]interface{}{
+		"type":                "string",
+		"enum":                []interface{}{"available", "sold"},
+		"x-enum-varnames":     []interface{}{"StatusAvailable", "StatusSold"},
+		"x-enum-descriptions": []interface{}{"available for sale", "sold out"},
+	}, res.schema("Status"))
+	require.Equal(t, map[string]interface{}{
+		"type":            "integer",
+		"enum":            []interface{}{1, 2, 4},
+		"x-enum-varnames": []interface{}{"LevelLow", "LevelMid", "LevelHigh"},
+	}, res.schema("Level"))
+	require.Equal(t, map[string]interface{}{
+		"type":                "string",
+		"enum":                []interface{}{"red", "blue"},
+		"x-enum-varnames":     []interface{}{"ColorRed", "ColorBlue"},
+		"x-enum-descriptions": []interface{}{"red color", ""},
+	}, res.schema("Color"))
+	pet := res.schema("Pet").(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"$ref": "#/components/schemas/Status",
+	}, pet["properties"].(map[string]interface{})["status"])
+}
+
+func TestUnion(t *testing.T) {
+	const src = `package api
+
+// (oneOf kind)
+type Shape interface {
+	isShape()
+}
+
+type Circle struct {
+	Kind string
+}
+
+type Square struct {
+	Kind string
+}
+
+func (Circle) isShape()  {}
+func (*Square) isShape() {}
+
+// (anyOf)
+// card: Card
+type PaymentMethod interface{}
+
+type Card struct {
+	Number string
+}
+`
+	res := generate(t, src, genspec.Config{})
+	require.NoError(t, res.err)
+	require.Equal(t, map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"$ref": "#/components/schemas/Circle"},
+			map[string]interface{}{"$ref": "#/components/schemas/Square"},
+		},
+		"discriminator": map[string]interface{}{
+			"propertyName": "kind",
+			"mapping": map[string]interface{}{
+				"circle": "#/components/schemas/Circle",
+				"square": "#/components/schemas/Square",
+			},
+		},
+	}, res.schema("Shape"))
+	require.Equal(t, map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"$ref": "#/components/schemas/Card"},
+		},
+	}, res.schema("PaymentMethod"))
+}
+
+const embeddedSrc = `package api
+
+import "time"
+
+type Base struct {
+	Id int64
+}
+
+type Audit struct {
+	CreatedBy string ` + "`json:\"created_by\"`" + `
+	Id        string
+}
+
+type Pet struct {
+	Base
+	*Audit
+	time.Location
+	Name   string
+	Secret string ` + "`json:\"-\"`" + `
+}
+`
+
+func TestEmbedded(t *testing.T) {
+	res := generate(t, embeddedSrc, genspec.Config{})
+	require.Equal(t, map[string]interface{}{
+		"allOf": []interface{}{
+			map[string]interface{}{"$ref": "#/components/schemas/Base"},
+			map[string]interface{}{"$ref": "#/components/schemas/Audit"},
+			map[string]interface{}{"$ref": "#/components/schemas/Location"},
+			map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+				"required":   []interface{}{"name"},
+			},
+		},
+	}, res.schema("Pet"))
+}
+
+func TestEmbeddedFlatten(t *testing.T) {
+	res := generate(t, embeddedSrc, genspec.Config{FlattenEmbedded: true})
+	require.Len(t, res.diags, 2)
+	require.Contains(t, res.diags[0].Message, "cannot flatten Location")
+	require.Contains(t, res.diags[1].Message, "unresolved ref #/components/schemas/Location")
+	require.Equal(t, map[string]interface{}{
+		"allOf": []interface{}{
+			map[string]interface{}{"$ref": "#/components/schemas/Location"},
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					// id is ambiguous between Base and Audit
+					"created_by": map[string]interface{}{"type": "string"},
+					"name":       map[string]interface{}{"type": "string"},
+				},
+				"required": []interface{}{"created_by", "name"},
+			},
+		},
+	}, res.schema("Pet"))
+}
+
+const openAPISpecSrc = "const OpenAPISpec = `\ninfo:\n  title: test\n  version: 1.0.0\n`\n"
+
+func TestNamedTypes(t *testing.T) {
+	const src = `package api
+
+import "time"
+
+` + openAPISpecSrc + `
+type Tags []string
+
+type Money int64
+
+type Labels = map[string]string
+
+type Pet struct {
+	Tags      Tags
+	Price     *Money
+	Owner     struct {
+		Name string
+	}
+	CreatedAt time.Time
+}
+`
+	res := generate(t, src, genspec.Config{})
+	require.NoError(t, res.err)
+	require.Empty(t, res.diags)
+	require.Equal(t, map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"type": "string"},
+	}, res.schema("Tags"))
+	require.Equal(t, map[string]interface{}{
+		"type":   "integer",
+		"format": "int64",
+	}, res.schema("Money"))
+	require.Equal(t, map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": map[string]interface{}{"type": "string"},
+	}, res.schema("Labels"))
+	require.Equal(t, map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"tags":  map[string]interface{}{"$ref": "#/components/schemas/Tags"},
+			"price": map[string]interface{}{"$ref": "#/components/schemas/Money"},
+			"owner": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+				"required":   []interface{}{"name"},
+			},
+			"createdAt": map[string]interface{}{"type": "string", "format": "date-time"},
+		},
+		"required": []interface{}{"tags", "price", "owner", "createdAt"},
+	}, res.schema("Pet"))
+}
+
+func TestDeclarationOrder(t *testing.T) {
+	const src = `package api
+
+type Interface interface {
+	// (GET /categories)
+	// 200: categories
+	// default: unexpected error
+	FindCategories(params FindCategoriesParams) []Category
+}
+
+type FindCategoriesParams struct {
+	Paging
+	Parent string
+}
+
+type Paging struct {
+	Limit int32
+}
+
+type Category struct {
+	Children []Category
+}
+
+type Comment struct {
+	Thread *Thread
+}
+
+type Thread struct {
+	Comments []Comment
+}
+
+type Error struct {
+	Message string
+}
+
+` + openAPISpecSrc
+	res := generate(t, src, genspec.Config{})
+	require.NoError(t, res.err)
+	require.Empty(t, res.diags)
+	paths := res.kv["paths"].(map[string]interface{})
+	get := paths["/categories"].(map[string]interface{})["get"].(map[string]interface{})
+	require.Equal(t, []interface{}{
+		map[string]interface{}{
+			"in":     "query",
+			"name":   "limit",
+			"schema": map[string]interface{}{"type": "integer", "format": "int32"},
+		},
+		map[string]interface{}{
+			"in":     "query",
+			"name":   "parent",
+			"schema": map[string]interface{}{"type": "string"},
+		},
+	}, get["parameters"])
+	require.Equal(t, map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"$ref": "#/components/schemas/Category"},
+	}, res.schema("Category").(map[string]interface{})["properties"].(map[string]interface{})["children"])
+}
+
+func TestCycle(t *testing.T) {
+	const src = `package api
+
+type A = B
+
+type B = A
+
+type Node struct {
+	*Node
+}
+`
+	res := generate(t, src, genspec.Config{FlattenEmbedded: true})
+	require.Len(t, res.diags, 2)
+	require.Equal(t, "embedding cycle on Node", res.diags[0].Message)
+	require.Equal(t, 8, res.diags[0].Pos.Line)
+	require.Equal(t, "cycle in schema definition: A -> B -> A", res.diags[1].Message)
+	require.Equal(t, 3, res.diags[1].Pos.Line)
+}
+
+func TestReadWrite(t *testing.T) {
+	const src = `package api
+
+type User struct {
+	Name     string
+	Password string // (writeOnly)
+}
+
+type Pet struct {
+	// (readOnly)
+	Id    int64
+	Name  string
+	Owner User ` + "`{readOnly:true}`" + `
+}
+
+type Interface interface {
+	// (POST /pets)
+	// 200: pet response
+	// default: unexpected error
+	AddPet(body Pet) []Pet
+}
+
+type Error struct {
+	Message string
+}
+
+` + openAPISpecSrc
+	res := generate(t, src, genspec.Config{})
+	require.NoError(t, res.err)
+	require.Empty(t, res.diags)
+	props := res.schema("Pet").(map[string]interface{})["properties"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{"type": "integer", "format": "int64", "readOnly": true}, props["id"])
+	require.Equal(t, map[string]interface{}{
+		"allOf":    []interface{}{map[string]interface{}{"$ref": "#/components/schemas/User"}},
+		"readOnly": true,
+	}, props["owner"])
+	require.Nil(t, res.schema("PetInput"))
+
+	res = generate(t, src, genspec.Config{SplitReadWrite: true})
+	require.NoError(t, res.err)
+	require.Empty(t, res.diags)
+	require.Equal(t, []interface{}{"name"}, res.schema("PetInput").(map[string]interface{})["required"])
+	require.Equal(t, []interface{}{"id", "name", "owner"}, res.schema("PetOutput").(map[string]interface{})["required"])
+	owner := res.schema("PetOutput").(map[string]interface{})["properties"].(map[string]interface{})["owner"]
+	require.Equal(t, map[string]interface{}{
+		"allOf":    []interface{}{map[string]interface{}{"$ref": "#/components/schemas/UserOutput"}},
+		"readOnly": true,
+	}, owner)
+	require.Equal(t, []interface{}{"name"}, res.schema("UserOutput").(map[string]interface{})["required"])
+
+	paths := res.kv["paths"].(map[string]interface{})
+	post := paths["/pets"].(map[string]interface{})["post"].(map[string]interface{})
+	body := post["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"]
+	require.Equal(t, map[string]interface{}{
+		"schema": map[string]interface{}{"$ref": "#/components/schemas/PetInput"},
+	}, body)
+	ok := post["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})["application/json"]
+	require.Equal(t, map[string]interface{}{
+		"schema": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"$ref": "#/components/schemas/PetOutput"},
+		},
+	}, ok)
+
+	// an existing schema of the name of a variant is not used for the variant.
+	res = generate(t, src+"\ntype PetOutput struct {\n\tOther string\n}\n", genspec.Config{SplitReadWrite: true})
+	require.NoError(t, res.err)
+	require.Len(t, res.diags, 1)
+	require.Contains(t, res.diags[0].Message, "PetOutput already exists")
+	require.Equal(t, []interface{}{"other"}, res.schema("PetOutput").(map[string]interface{})["required"])
+	paths = res.kv["paths"].(map[string]interface{})
+	post = paths["/pets"].(map[string]interface{})["post"].(map[string]interface{})
+	ok = post["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})["application/json"]
+	require.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/Pet"},
+		ok.(map[string]interface{})["schema"].(map[string]interface{})["items"])
+	body = post["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"]
+	require.Equal(t, map[string]interface{}{
+		"schema": map[string]interface{}{"$ref": "#/components/schemas/PetInput"},
+	}, body)
+}
+
+func TestValidateTag(t *testing.T) {
+	const src = `package api
+
+type Pet struct {
+	Name   string   ` + "`validate:\"required,min=1,max=64\"`" + `
+	Email  string   ` + "`validate:\"omitempty,email\"`" + `
+	Status string   ` + "`validate:\"required,oneof=available sold\"`" + `
+	Tags   []string ` + "`validate:\"max=3,unique,dive,max=10\"`" + `
+}
+`
+	res := generate(t, src, genspec.Config{})
+	require.Equal(t, map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name":   map[string]interface{}{"type": "string", "minLength": 1, "maxLength": 64},
+			"email":  map[string]interface{}{"type": "string", "format": "email"},
+			"status": map[string]interface{}{"type": "string", "enum": []interface{}{"available", "sold"}},
+			"tags": map[string]interface{}{
+				"type":        "array",
+				"maxItems":    3,
+				"uniqueItems": true,
+				"items":       map[string]interface{}{"type": "string", "maxLength": 10},
+			},
+		},
+		"required": []interface{}{"name", "status"},
+	}, res.schema("Pet"))
+}
+
+func TestTagErrors(t *testing.T) {
+	const src = `package api
+
+type Pet struct {
+	Name string ` + "`{min:10,max:10`" + `
+	Tag  string ` + "`{maxLenght:10}`" + `
+}
+`
+	res := generate(t, src, genspec.Config{StrictTags: true})
+	require.Len(t, res.diags, 2)
+	require.Equal(t, 4, res.diags[0].Pos.Line)
+	require.Equal(t, 28, res.diags[0].Pos.Column)
+	require.Equal(t, "invalid tag at 13: unexpected end of JSON input", res.diags[0].Message)
+	require.Equal(t, 5, res.diags[1].Pos.Line)
+	require.Equal(t, `unknown key "maxLenght", did you mean "maxLength"?`, res.diags[1].Message)
+}
+
+func TestExamples(t *testing.T) {
+	const src = `package api
+
+type Status string
+
+const StatusSold Status = "sold"
+
+type NewPet struct {
+	Name   string   ` + "`example:\"fluffy\"`" + `
+	Age    int      ` + "`default:\"1\"`" + `
+	Tags   []string ` + "`example:\"cat,white\"`" + `
+	Status Status   ` + "`example:\"sold\"`" + `
+}
+
+// (example ExamplePet)
+type Pet struct {
+	NewPet
+	Id int64
+}
+
+type FindPetsParams struct {
+	Limit int32 ` + "`example:\"10\"`" + `
+}
+
+var ExampleNewPet = NewPet{Name: "fluffy", Tags: []string{"cat"}, Status: StatusSold}
+
+var ExamplePet = &Pet{
+	NewPet: ExampleNewPet,
+	Id:     1,
+}
+
+type Interface interface {
+	// (POST /pets)
+	// 200: pet response
+	// default: unexpected error
+	// examples:
+	//   body: [ExampleNewPet]
+	//   200: ExamplePet
+	AddPet(body NewPet) Pet
+
+	// (GET /pets)
+	// 200: pet response
+	// default: unexpected error
+	FindPets(params FindPetsParams) []Pet
+}
+
+type Error struct {
+	Message string
+}
+
+` + openAPISpecSrc
+	res := generate(t, src, genspec.Config{})
+	require.NoError(t, res.err)
+	require.Empty(t, res.diags)
+
+	pet := map[string]interface{}{"name": "fluffy", "tags": []interface{}{"cat"}, "status": "sold", "id": 1}
+	require.Equal(t, pet, res.schema("Pet").(map[string]interface{})["example"])
+
+	props := res.schema("NewPet").(map[string]interface{})["properties"].(map[string]interface{})
+	require.Equal(t, "fluffy", props["name"].(map[string]interface{})["example"])
+	require.Equal(t, 1, props["age"].(map[string]interface{})["default"])
+	require.Equal(t, []interface{}{"cat", "white"}, props["tags"].(map[string]interface{})["example"])
+	require.Equal(t, map[string]interface{}{
+		"allOf":   []interface{}{map[string]interface{}{"$ref": "#/components/schemas/Status"}},
+		"example": "sold",
+	}, props["status"])
+
+	paths := res.kv["paths"].(map[string]interface{})
+	post := paths["/pets"].(map[string]interface{})["post"].(map[string]interface{})
+	body := post["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"ExampleNewPet": map[string]interface{}{
+			"value": map[string]interface{}{"name": "fluffy", "tags": []interface{}{"cat"}, "status": "sold"},
+		},
+	}, body["examples"])
+	ok := post["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"ExamplePet": map[string]interface{}{"value": pet},
+	}, ok["examples"])
+
+	get := paths["/pets"].(map[string]interface{})["get"].(map[string]interface{})
+	require.Equal(t, 10, get["parameters"].([]interface{})[0].(map[string]interface{})["example"])
+}
+
+func TestExampleErrors(t *testing.T) {
+	const src = `package api
+
+import "fmt"
+
+type Pet struct {
+	Name string
+}
+
+var ExampleJoined = Pet{Name: fmt.Sprint("a", "b")}
+
+var ExampleOther = other.Cat{
+	Name: "fluffy",
+}
+
+type Interface interface {
+	// (POST /pets)
+	// 200: pet response
+	// default: unexpected error
+	// examples:
+	//   body: [ExampleJoined, ExampleOther]
+	AddPet(body Pet) Pet
+}
+
+type Error struct {
+	Message string
+}
+
+` + openAPISpecSrc
+	res := generate(t, src, genspec.Config{})
+	require.Len(t, res.diags, 2)
+	require.Contains(t, res.diags[0].Message, `cannot evaluate fmt.Sprint("a", "b") at `)
+	require.Contains(t, res.diags[0].Message, "spec.go:9:31")
+	require.Contains(t, res.diags[1].Message, `cannot evaluate other.Cat{…} at `)
+	require.Contains(t, res.diags[1].Message, "spec.go:11:20")
+}
+
+func TestExtensions(t *testing.T) {
+	const src = `package api
+
+const OpenAPISpec = ` + "`" + `
+info:
+  title: test
+  version: 1.0.0
+x-amazon-apigateway-request-validator: all
+` + "`" + `
+
+// (extensions)
+// x-go-type: decimal.Decimal
+type Money string
+
+type Pet struct {
+	Name  string ` + "`{\"x-oapi-codegen-extra-tags\": {db: \"name\"}}`" + `
+	Price Money
+	// (extensions)
+	// x-order: 1
+	Id int64
+}
+
+type FindPetsParams struct {
+	Limit int32
+}
+
+type Interface interface {
+	// (GET /pets)
+	// x-internal: true
+	// parameters:
+	//   limit: {x-go-name: MaxItems}
+	// 200:
+	//   description: pet response
+	//   x-cache: 60
+	// default: unexpected error
+	FindPets(params FindPetsParams) []Pet
+}
+
+type Error struct {
+	Message string
+}
+`
+	res := generate(t, src, genspec.Config{})
+	require.NoError(t, res.err)
+	require.Empty(t, res.diags)
+	require.Equal(t, "all", res.kv["x-amazon-apigateway-request-validator"])
+	require.Equal(t, "decimal.Decimal", res.schema("Money").(map[string]interface{})["x-go-type"])
+
+	props := res.schema("Pet").(map[string]interface{})["properties"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{"db": "name"}, props["name"].(map[string]interface{})["x-oapi-codegen-extra-tags"])
+	require.Equal(t, 1, props["id"].(map[string]interface{})["x-order"])
+
+	paths := res.kv["paths"].(map[string]interface{})
+	get := paths["/pets"].(map[string]interface{})["get"].(map[string]interface{})
+	require.Equal(t, true, get["x-internal"])
+	require.Equal(t, "MaxItems", get["parameters"].([]interface{})[0].(map[string]interface{})["x-go-name"])
+	ok := get["responses"].(map[string]interface{})["200"].(map[string]interface{})
+	require.Equal(t, "pet response", ok["description"])
+	require.Equal(t, 60, ok["x-cache"])
+}
+
+func TestRootDeclarations(t *testing.T) {
+	const src = `package api
+
+const OpenAPISpec = ` + "`" + `
+info:
+  title: test
+  version: 1.0.0
+servers:
+- url: https://old.example.com
+x-logo: logo.png
+` + "`" + `
+
+const Servers = ` + "`" + `
+- url: https://{env}.example.com/v1
+  description: per environment
+  variables:
+    env:
+      default: api
+      enum: [api, staging]
+- http://localhost:8080
+` + "`" + `
+
+const Tags = ` + "`" + `
+- name: pets
+  description: Everything about pets
+- store
+` + "`" + `
+
+const ExternalDocs = "https://example.com/docs"
+
+type Pet struct {
+	Name string
+}
+
+type Interface interface {
+	// (GET /pets)
+	// tags: pets
+	// 200: pet response
+	// default: unexpected error
+	FindPets() []Pet
+	// (GET /orders)
+	// tags: [store, orders]
+	// 200: order response
+	// default: unexpected error
+	FindOrders() []Pet
+}
+
+type Error struct {
+	Message string
+}
+`
+	res := generate(t, src, genspec.Config{})
+	require.NoError(t, res.err)
+	require.Len(t, res.diags, 2)
+	require.Equal(t, genspec.SeverityWarning, res.diags[0].Severity)
+	require.Equal(t, "Servers overrides the servers of OpenAPISpec", res.diags[0].Message)
+	require.Equal(t, 12, res.diags[0].Pos.Line)
+	require.Equal(t, `tag "orders" is not declared in Tags`, res.diags[1].Message)
+
+	require.Equal(t, "logo.png", res.kv["x-logo"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{
+			"url":         "https://{env}.example.com/v1",
+			"description": "per environment",
+			"variables": map[string]interface{}{
+				"env": map[string]interface{}{"default": "api", "enum": []interface{}{"api", "staging"}},
+			},
+		},
+		map[string]interface{}{"url": "http://localhost:8080"},
+	}, res.kv["servers"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"name": "pets", "description": "Everything about pets"},
+		map[string]interface{}{"name": "store"},
+	}, res.kv["tags"])
+	require.Equal(t, map[string]interface{}{"url": "https://example.com/docs"}, res.kv["externalDocs"])
+
+	paths := res.kv["paths"].(map[string]interface{})
+	get := paths["/pets"].(map[string]interface{})["get"].(map[string]interface{})
+	require.Equal(t, []interface{}{"pets"}, get["tags"])
+}
+
+func TestRootDeclarationErrors(t *testing.T) {
+	const src = `package api
+
+const OpenAPISpec = ` + "`" + `
+info:
+  title: test
+  version: 1.0.0
+` + "`" + `
+
+const Servers = ` + "`" + `
+- url: https://{env}.example.com
+` + "`" + `
+
+const Tags = ` + "`" + `
+- pets
+- pets
+` + "`" + `
+
+type Error struct {
+	Message string
+}
+`
+	res := generate(t, src, genspec.Config{})
+	require.NoError(t, res.err)
+	messages := []string{}
+	for _, d := range res.diags {
+		messages = append(messages, fmt.Sprintf("%v: %v", d.Pos.Line, d.Message))
+	}
+	require.Equal(t, []string{
+		"9: invalid server 0: server has undeclared variables",
+		"13: duplicate tag \"pets\"",
+	}, messages)
+}
+
+func TestOperationErrors(t *testing.T) {
+	const src = `package api
+
+const Auth = "oauth2: [\n"
+
+type Error struct {
+	Message string
+}
+
+type Interface interface {
+	// Find pets.
+	FindPets() []Error
+
+	// (GET /pets/{id})
+	// 200: [
+	FindPet(id int64) Error
+
+	// (DELETE /pets/{id})
+	DeletePet(int64)
+
+	// (POST /pets)
+	// 200: pet response
+	// default: unexpected error
+	AddPet(body Error) Error
+}
+`
+	res := generate(t, src, genspec.Config{})
+	require.NoError(t, res.err)
+	messages := []string{}
+	for _, d := range res.diags {
+		messages = append(messages, fmt.Sprintf("%v: %v", d.Pos.Line, d.Message))
+	}
+	require.Len(t, messages, 4)
+	require.Contains(t, messages[0], "3: Auth: ")
+	require.Equal(t, "11: FindPets: no (METHOD /path) in the doc", messages[1])
+	require.Contains(t, messages[2], "15: FindPet: (GET /pets/{id}): yaml: ")
+	require.Equal(t, "18: DeletePet: the parameters must be named", messages[3])
+	paths := res.kv["paths"].(map[string]interface{})
+	require.Len(t, paths, 1)
+	require.NotNil(t, paths["/pets"])
+}
+
+func TestAuthErrors(t *testing.T) {
+	tests := []struct {
+		auth string
+		err  string
+	}{
+		{`"foo"`, `Auth: unknown security scheme "foo"`},
+		{`"header"`, "Auth: header must have 2 values separated by commas: header"},
+		{`"apiKey, header"`, "Auth: apiKey must have 3 values separated by commas: apiKey, header"},
+		{`"oidc"`, "Auth: oidc must have 2 values separated by commas: oidc"},
+		{`"[basic]"`, "Auth: must be a string or a map: [basic]"},
+		{"`petstore: {flow: implicit, tokenUrl: 1}`", "oauth2: tokenUrl must be a string: 1"},
+		{"`petstore: {1: implicit}`", "oauth2: invalid key 1"},
+		{"`petstore: {flow: implicit, scopes: [read]}`", "oauth2: scopes: "},
+	}
+	for _, tt := range tests {
+		src := "package api\n\nconst Auth = " + tt.auth + "\n\ntype Error struct {\n\tMessage string\n}\n" + openAPISpecSrc
+		res := generate(t, src, genspec.Config{})
+		require.NoError(t, res.err, tt.auth)
+		require.Len(t, res.diags, 1, tt.auth)
+		require.Equal(t, 3, res.diags[0].Pos.Line, tt.auth)
+		require.Contains(t, res.diags[0].Message, tt.err, tt.auth)
+	}
+}
+
+func TestMergeComponents(t *testing.T) {
+	const src = `package api
+
+const OpenAPISpec = ` + "`" + `
+info:
+  title: test
+  version: 1.0.0
+components:
+  schemas:
+    Geometry:
+      type: object
+      properties:
+        type: {type: string}
+        coordinates: {type: array, items: {type: number}}
+    Place:
+      description: a named place
+      properties:
+        name: {type: string, maxLength: 64}
+      x-order: 1
+    Error:
+      type: object
+  responses:
+    NotFound:
+      description: not found
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Error'
+` + "`" + `
+
+type Place struct {
+	Name     string
+	Geometry Geometry
+}
+
+type Error struct {
+	Message string
+}
+`
+	res := generate(t, src, genspec.Config{})
+	require.NoError(t, res.err)
+	require.Empty(t, res.diags)
+	require.Equal(t, "object", res.schema("Geometry").(map[string]interface{})["type"])
+	place := res.schema("Place").(map[string]interface{})
+	require.Equal(t, "a named place", place["description"])
+	require.Equal(t, 1, place["x-order"])
+	require.Equal(t, map[string]interface{}{"type": "string", "maxLength": 64}, place["properties"].(map[string]interface{})["name"])
+	require.Contains(t, place["properties"], "geometry")
+	require.NotNil(t, res.schema("Error"))
+	components := res.kv["components"].(map[string]interface{})
+	notFound := components["responses"].(map[string]interface{})["NotFound"].(map[string]interface{})
+	require.Equal(t, "not found", notFound["description"])
+}
+
+func TestMergeComponentsConflict(t *testing.T) {
+	const src = `package api
+
+const OpenAPISpec = ` + "`" + `
+info:
+  title: test
+  version: 1.0.0
+components:
+  schemas:
+    Error:
+      type: string
+      properties:
+        message: {type: string}
+        code: {type: integer}
+` + "`" + `
+
+type Error struct {
+	Message string
+}
+`
+	res := generate(t, src, genspec.Config{})
+	require.NoError(t, res.err)
+	require.Len(t, res.diags, 1)
+	require.Equal(t, 3, res.diags[0].Pos.Line)
+	require.Contains(t, res.diags[0].Message, `schemas "Error" of OpenAPISpec is also generated at `)
+	require.Contains(t, res.diags[0].Message, "spec.go:16:6, type differs")
+	require.Contains(t, res.schema("Error").(map[string]interface{})["properties"], "code")
+	require.Equal(t, "object", res.schema("Error").(map[string]interface{})["type"])
+}
+
+func TestOverlays(t *testing.T) {
+	const src = `package api
+
+const OpenAPISpec = ` + "`" + `
+info:
+  title: test
+  version: 1.0.0
+` + "`" + `
+
+type Pet struct {
+	Name string
+}
+
+type Interface interface {
+	// (GET /pets)
+	// 200: pet response
+	// default: unexpected error
+	FindPets() []Pet
+	// (GET /internal/pets)
+	// 200: pet response
+	// default: unexpected error
+	FindInternalPets() []Pet
+}
+
+type Error struct {
+	Message string
+}
+`
+	const gateway = `overlay: 1.0.0
+info:
+  title: gateway
+  version: 1.0.0
+actions:
+- target: $.paths.*[?(@.operationId == 'FindPets')]
+  update:
+    x-amazon-apigateway-integration:
+      type: http_proxy
+- target: $.paths['/internal/pets']
+  remove: true
+- target: $.servers
+  update: {url: https://staging.example.com}
+`
+	const env = `overlay: 1.0.0
+info:
+  title: env
+  version: 1.0.0
+actions:
+- target: $.info
+  update: {x-env: staging}
+`
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "gateway.yaml"), filepath.Join(dir, "env.yaml")}
+	Must(os.WriteFile(files[0], []byte(gateway), 0644))
+	Must(os.WriteFile(files[1], []byte(env), 0644))
+
+	res := generate(t, src, genspec.Config{Overlays: files})
+	require.NoError(t, res.err)
+	require.Len(t, res.diags, 1)
+	require.Equal(t, genspec.SeverityWarning, res.diags[0].Severity)
+	require.Contains(t, res.diags[0].Message, "action 2: target $.servers matches nothing")
+
+	paths := res.kv["paths"].(map[string]interface{})
+	require.NotContains(t, paths, "/internal/pets")
+	get := paths["/pets"].(map[string]interface{})["get"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{"type": "http_proxy"}, get["x-amazon-apigateway-integration"])
+	require.Equal(t, "staging", res.kv["info"].(map[string]interface{})["x-env"])
+
+	// the $refs of the overlaid spec are resolved.
+	spec, _, err := genspec.Generate(context.Background(), &genspec.Config{
+		InputFile: "spec.go",
+		Source:    src,
+		Overlays:  files,
+	})
+	require.NoError(t, err)
+	schema := spec.Paths["/pets"].Get.Responses["200"].Value.Content["application/json"].Schema
+	require.Equal(t, "#/components/schemas/Pet", schema.Value.Items.Ref)
+	require.NotNil(t, schema.Value.Items.Value)
+	require.Equal(t, "string", schema.Value.Items.Value.Properties["name"].Value.Type)
+
+	// a failing action fails the generation.
+	bad := filepath.Join(dir, "bad.yaml")
+	Must(os.WriteFile(bad, []byte("overlay: 1.0.0\nactions:\n- {target: $.info.title, update: x}\n"), 0644))
+	res = generate(t, src, genspec.Config{Overlays: []string{bad}})
+	require.EqualError(t, res.err, bad+`: action 0: $["info"]["title"]: cannot update test`)
+}
+
+func TestGenerate(t *testing.T) {
+	const src = "package api\n" + openAPISpecSrc + `
+type Pet struct {
+	Name string ` + "`validate:\"min=1\"`" + `
+}
+
+type Error struct {
+	Message string
+}
+`
+	spec, diags, err := genspec.Generate(context.Background(), &genspec.Config{
+		InputFile: "spec.go",
+		Source:    src,
+	})
+	require.NoError(t, err)
+	require.Empty(t, diags)
+	require.Equal(t, "3.0.0", spec.OpenAPI)
+	pet := spec.Components.Schemas["Pet"].Value
+	require.Equal(t, uint64(1), pet.Properties["name"].Value.MinLength)
+
+	b := bytes.Buffer{}
+	require.NoError(t, genspec.WriteTo(&b, spec, genspec.FormatJSON))
+	var obj map[string]interface{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &obj))
+	require.Equal(t, "3.0.0", obj["openapi"])
+
+	b.Reset()
+	require.NoError(t, genspec.WriteTo(&b, spec, genspec.FormatYAML))
+	require.True(t, strings.HasPrefix(b.String(), "openapi: 3.0.0\ninfo:\n"))
+
+	require.Error(t, genspec.WriteTo(&b, spec, "toml"))
+	require.Equal(t, genspec.FormatJSON, genspec.FormatOf("openapi.JSON"))
+	require.Equal(t, genspec.FormatYAML, genspec.FormatOf("openapi.yml"))
+
+	_, _, err = genspec.Generate(context.Background(), &genspec.Config{
+		InputFile: "spec.go",
+		Source:    "package",
+	})
+	require.Error(t, err)
+
+	spec, _, err = genspec.Generate(context.Background(), &genspec.Config{
+		InputFile: "spec.go",
+		Source:    src,
+		OpenAPI:   "3.0.3",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "3.0.3", spec.OpenAPI)
+
+	_, _, err = genspec.Generate(context.Background(), &genspec.Config{
+		InputFile: "spec.go",
+		Source:    src,
+		OpenAPI:   "3.1.0",
+	})
+	require.EqualError(t, err, `unsupported OpenAPI version "3.1.0", only 3.0.x is supported`)
+}
+
+func TestHooks(t *testing.T) {
+	const src = "package api\n\nimport \"github.com/shopspring/decimal\"\n" + openAPISpecSrc + `
+type Pet struct {
+	Name  string
+	Price decimal.Decimal
+}
+
+type Pets []Pet
+
+type Interface interface {
+	// (GET /pets)
+	// 200: pet response
+	// default: unexpected error
+	FindPets() Pets
+}
+
+type Error struct {
+	Message string
+}
+`
+	// formats other than OpenAPI's must be known to validate.
+	openapi3.DefineStringFormat("decimal", `^-?[0-9]+(\.[0-9]+)?$`)
+	config := &genspec.Config{
+		InputFile: "spec.go",
+		Source:    src,
+		TypeMappers: []genspec.TypeMapper{
+			genspec.SchemaMap{"decimal.Decimal": {Type: "string", Format: "decimal"}},
+		},
+	}
+	g, err := genspec.NewGenerator(config)
+	require.NoError(t, err)
+	g.AddTypeMapper(genspec.TypeMapperFunc(func(name string) *openapi3.Schema {
+		if name == "string" {
+			return &openapi3.Schema{Type: "string", MaxLength: openapi3.Uint64Ptr(255)}
+		}
+		return nil
+	}))
+	g.OnField(func(h *genspec.FieldHook) error {
+		h.Name = strcase.ToSnake(h.Name)
+		return nil
+	})
+	g.OnSchema(func(h *genspec.SchemaHook) error {
+		if h.Name != "Error" {
+			h.Name += "Model"
+		}
+		return genspec.SetExtensions(&h.Schema.Value.ExtensionProps, genspec.KeyValue{"x-org": "acme"})
+	})
+	g.OnOperation(func(h *genspec.OperationHook) error {
+		h.Operation.Tags = append(h.Operation.Tags, strings.TrimPrefix(h.Path, "/"))
+		return nil
+	})
+	spec, err := g.Generate(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, g.Diagnostics())
+
+	require.Nil(t, spec.Components.Schemas["Pet"])
+	pet := spec.Components.Schemas["PetModel"].Value
+	require.Equal(t, "decimal", pet.Properties["price"].Value.Format)
+	require.Equal(t, uint64(255), *pet.Properties["name"].Value.MaxLength)
+	require.Equal(t, "acme", pet.Extensions["x-org"])
+	require.Equal(t, "#/components/schemas/PetModel", spec.Components.Schemas["PetsModel"].Value.Items.Ref)
+
+	get := spec.Paths["/pets"].Get
+	require.Equal(t, []string{"pets"}, get.Tags)
+	require.Equal(t, "#/components/schemas/PetsModel", get.Responses["200"].Value.Content["application/json"].Schema.Ref)
+	require.Equal(t, "#/components/schemas/Error", get.Responses["default"].Value.Content["application/json"].Schema.Ref)
+
+	// the default types are not changed by the mappers.
+	require.Nil(t, genspec.DefaultTypes()["string"].MaxLength)
+}
+
+func TestUnsignedTypes(t *testing.T) {
+	const src = "package api\n" + openAPISpecSrc + `
+type Counter struct {
+	Small uint32
+	Large uint64 ` + "`validate:\"gte=1\"`" + `
+}
+`
+	res := generate(t, src, genspec.Config{})
+	require.Empty(t, res.diags)
+	props := res.schema("Counter").(map[string]interface{})["properties"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{"type": "integer", "format": "int64", "minimum": 0}, props["small"])
+	require.Equal(t, map[string]interface{}{"type": "integer", "minimum": 1}, props["large"])
+}
+
+func TestHooksAfterSplit(t *testing.T) {
+	const src = "package api\n" + openAPISpecSrc + `
+//openapi:ignore response-inline-object
+type Pet struct {
+	// (readOnly)
+	Id   int64
+	Name string
+}
+
+type Interface interface {
+	// (POST /pets)
+	// 200: pet response
+	// default: unexpected error
+	AddPet(body Pet) Pet
+}
+
+type Error struct {
+	Message string
+}
+`
+	g, err := genspec.NewGenerator(&genspec.Config{InputFile: "spec.go", Source: src, SplitReadWrite: true})
+	require.NoError(t, err)
+	names := []string{}
+	g.OnSchema(func(h *genspec.SchemaHook) error {
+		names = append(names, h.Name)
+		if h.Name == "Pet" {
+			h.Name = "PetModel"
+		}
+		return nil
+	})
+	spec, err := g.Generate(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, g.Diagnostics())
+	require.Equal(t, []string{"Error", "Pet", "PetInput", "PetOutput"}, names)
+	require.NotNil(t, spec.Components.Schemas["PetModel"])
+
+	// the ignores move with the renamed schema.
+	require.True(t, g.Ignored(genspec.Pointer("components", "schemas", "PetModel", "properties", "id"), "response-inline-object"))
+	require.False(t, g.Ignored(genspec.Pointer("components", "schemas", "Pet"), "response-inline-object"))
+	require.True(t, g.Ignored(genspec.Pointer("components", "schemas", "PetInput"), "response-inline-object"))
+	require.Equal(t, 9, g.Position(genspec.Pointer("components", "schemas", "PetModel")).Line)
+}
+
+func TestHooksRenameConflict(t *testing.T) {
+	const src = "package api\n" + openAPISpecSrc + `
+type Pet struct {
+	Name string
+}
+
+type Error struct {
+	Message string
+}
+`
+	_, diags, err := genspec.Generate(context.Background(), &genspec.Config{
+		InputFile: "spec.go",
+		Source:    src,
+		Hooks: genspec.Hooks{
+			OnSchema: []func(*genspec.SchemaHook) error{
+				func(h *genspec.SchemaHook) error {
+					if h.Name == "Pet" {
+						h.Name = "Error"
+					}
+					return nil
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, diags, 1)
+	require.Equal(t, `cannot rename Pet to "Error"`, diags[0].Message)
+	require.Equal(t, 8, diags[0].Pos.Line)
+}
+
+type reflectBase struct {
+	ID int64 `json:"id" scheme:"readOnly:true"`
+}
+
+type Money int64
+
+type Pet struct {
+	reflectBase
+	Name     string            `validate:"required,min=1,max=64"`
+	Tag      *string           `json:"tag,omitempty" validate:"omitempty,oneof=cat dog"`
+	Price    Money             `scheme:"min:0" example:"100"`
+	Tags     []string          `validate:"dive,min=1" scheme:"max:10"`
+	Photo    []byte            `json:"photo,omitempty" validate:"omitempty"`
+	Born     time.Time         `json:"born"`
+	Extra    map[string]string `json:"extra,omitempty" validate:"omitempty"`
+	Parent   *Pet              `json:"parent,omitempty" validate:"omitempty"`
+	internal string
+}
+
+type FindPetsParams struct {
+	Limit int32 `validate:"required,gte=1" example:"10"`
+	Tag   string
+}
+
+func TestReflector(t *testing.T) {
+	// the same declarations as the Go types above.
+	const src = "package api\n\nimport \"time\"\n" + openAPISpecSrc + `
+type reflectBase struct {
+	ID int64 ` + "`json:\"id\" scheme:\"readOnly:true\"`" + `
+}
+
+type Money int64
+
+type Pet struct {
+	reflectBase
+	Name     string            ` + "`validate:\"required,min=1,max=64\"`" + `
+	Tag      *string           ` + "`json:\"tag,omitempty\" validate:\"omitempty,oneof=cat dog\"`" + `
+	Price    Money             ` + "`scheme:\"min:0\" example:\"100\"`" + `
+	Tags     []string          ` + "`validate:\"dive,min=1\" scheme:\"max:10\"`" + `
+	Photo    []byte            ` + "`json:\"photo,omitempty\" validate:\"omitempty\"`" + `
+	Born     time.Time         ` + "`json:\"born\"`" + `
+	Extra    map[string]string ` + "`json:\"extra,omitempty\" validate:\"omitempty\"`" + `
+	Parent   *Pet              ` + "`json:\"parent,omitempty\" validate:\"omitempty\"`" + `
+}
+
+type FindPetsParams struct {
+	Limit int32 ` + "`validate:\"required,gte=1\" example:\"10\"`" + `
+	Tag   string
+}
+
+type Interface interface {
+	// (GET /pets)
+	// 200: pet response
+	FindPets(params FindPetsParams) []Pet
+}
+`
+	for _, flatten := range []bool{false, true} {
+		spec, _, err := genspec.Generate(context.Background(), &genspec.Config{
+			InputFile:       "spec.go",
+			Source:          src,
+			FlattenEmbedded: flatten,
+		})
+		require.NoError(t, err)
+
+		r := genspec.NewReflector()
+		r.FlattenEmbedded = flatten
+		err = r.AddOperation(&genspec.Operation{
+			Method:      "get",
+			Path:        "/pets",
+			OperationID: "FindPets",
+			Params:      FindPetsParams{},
+			Responses: map[string]*genspec.Response{
+				"200": {Description: "pet response", Body: []Pet{}},
+			},
+		})
+		require.NoError(t, err)
+		got := r.Spec()
+
+		for _, name := range []string{"Pet", "Money", "reflectBase"} {
+			if flatten && name == "reflectBase" {
+				continue
+			}
+			require.Equal(t, toJSON(spec.Components.Schemas[name]), toJSON(got.Components.Schemas[name]), name)
+		}
+		if flatten {
+			require.Nil(t, got.Components.Schemas["reflectBase"])
+		}
+		want := spec.Paths["/pets"].Get
+		require.Equal(t, toJSON(want.Parameters), toJSON(got.Paths["/pets"].Get.Parameters))
+		require.Equal(t, toJSON(want.Responses["200"]), toJSON(got.Paths["/pets"].Get.Responses["200"]))
+	}
+}
+
+type reflectEvent struct {
+	time.Time
+	Name string
+}
+
+func TestEmbeddedMapped(t *testing.T) {
+	// a mapped type is a field, not a parent.
+	const src = "package api\n\nimport \"time\"\n" + openAPISpecSrc + `
+type reflectEvent struct {
+	time.Time
+	Name string
+}
+`
+	want := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"time": map[string]interface{}{"type": "string", "format": "date-time"},
+			"name": map[string]interface{}{"type": "string"},
+		},
+		"required": []interface{}{"time", "name"},
+	}
+	for _, flatten := range []bool{false, true} {
+		res := generate(t, src, genspec.Config{FlattenEmbedded: flatten})
+		require.Empty(t, res.diags)
+		require.Equal(t, want, res.schema("reflectEvent"))
+		require.NotContains(t, res.kv["components"].(map[string]interface{})["schemas"], "Time")
+
+		r := genspec.NewReflector()
+		r.FlattenEmbedded = flatten
+		_, err := r.Schema(reflectEvent{})
+		require.NoError(t, err)
+		require.JSONEq(t, toJSON(want), toJSON(r.Spec().Components.Schemas["reflectEvent"]))
+		require.NotContains(t, r.Spec().Components.Schemas, "Time")
+	}
+}
+
+func TestReflectorOperations(t *testing.T) {
+	r := genspec.NewReflector()
+	err := r.AddOperation(&genspec.Operation{
+		Method:     "PUT",
+		Path:       "/pets/{id}/photos/{name}",
+		PathParams: struct{ ID int64 }{},
+		Body:       &Pet{},
+		Responses: map[string]*genspec.Response{
+			"204":     {Description: "updated"},
+			"default": {Description: "unexpected error", Body: struct{ Message string }{}},
+		},
+	})
+	require.NoError(t, err)
+	put := r.Spec().Paths["/pets/{id}/photos/{name}"].Put
+	require.Equal(t, "integer", put.Parameters.GetByInAndName("path", "id").Schema.Value.Type)
+	require.Equal(t, "string", put.Parameters.GetByInAndName("path", "name").Schema.Value.Type)
+	require.Equal(t, "#/components/schemas/Pet", put.RequestBody.Value.Content["application/json"].Schema.Ref)
+	require.Nil(t, put.Responses["204"].Value.Content)
+	require.Equal(t, "object", put.Responses["default"].Value.Content["application/json"].Schema.Value.Type)
+
+	r.Spec().Info = &openapi3.Info{Title: "test", Version: "1.0.0"}
+	loaded, err := openapi3.NewLoader().LoadFromData([]byte(toJSON(r.Spec())))
+	require.NoError(t, err)
+	require.NoError(t, loaded.Validate(context.Background()))
+
+	for _, op := range []*genspec.Operation{
+		{Method: "PUT", Path: "/pets/{id}/photos/{name}", Responses: map[string]*genspec.Response{"204": {}}},
+		{Method: "GET", Path: "/pets"},
+		{Method: "GET", Path: "/pets", PathParams: struct{ ID int64 }{}, Responses: map[string]*genspec.Response{"200": {}}},
+		{Method: "GET", Path: "/chan", Responses: map[string]*genspec.Response{"200": {Body: struct{ C chan int }{}}}},
+		{Method: "GET", Path: "/tag", Responses: map[string]*genspec.Response{"200": {Body: struct {
+			N string `scheme:"min:"`
+		}{}}}},
+		{Method: "GET", Path: "/code", Responses: map[string]*genspec.Response{"ok": {}}},
+	} {
+		require.Error(t, r.AddOperation(op), op.Path)
+	}
+	require.Len(t, r.Spec().Paths, 1)
+}
+
+func toJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	Must(err)
+	return string(b)
+}
+
+func TestCheckFile(t *testing.T) {
+	const src = "package api\n" + openAPISpecSrc + `
+type Pet struct {
+	Name string
+	Age  int
+}
+`
+	spec, _, err := genspec.Generate(context.Background(), &genspec.Config{
+		InputFile: "spec.go",
+		Source:    src,
+	})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "openapi.json")
+	diff, err := genspec.CheckFile(spec, file)
+	require.NoError(t, err)
+	require.Contains(t, diff, "+openapi: 3.0.0\n")
+
+	// the format and the order of the keys do not matter.
+	b := bytes.Buffer{}
+	require.NoError(t, genspec.WriteTo(&b, spec, genspec.FormatJSON))
+	Must(os.WriteFile(file, b.Bytes(), 0644))
+	diff, err = genspec.CheckFile(spec, file)
+	require.NoError(t, err)
+	require.Equal(t, "", diff)
+	Must(os.WriteFile(file, []byte(`
+components:
+  schemas:
+    Pet:
+      required: [name, age]
+      type: object
+      properties: {name: {type: string}, age: {type: integer}}
+info: {version: 1.0.0, title: test}
+paths: {}
+openapi: "3.0.0"
+`), 0644))
+	diff, err = genspec.CheckFile(spec, file)
+	require.NoError(t, err)
+	require.Equal(t, "", diff)
+
+	spec.Components.Schemas["Pet"].Value.Properties["name"].Value.MaxLength = openapi3.Uint64Ptr(10)
+	diff, err = genspec.CheckFile(spec, file)
+	require.NoError(t, err)
+	require.Equal(t, "--- "+file+"\n+++ generated\n@@ -5,6 +5,7 @@\n"+
+		"         age:\n           type: integer\n         name:\n+          maxLength: 10\n           type: string\n"+
+		"       required:\n       - name\n", diff)
+}
+
+func TestRunCheck(t *testing.T) {
+	const src = "package api\n" + openAPISpecSrc
+	dir := t.TempDir()
+	input := filepath.Join(dir, "spec.go")
+	Must(os.WriteFile(input, []byte(src), 0644))
+	config := &genspec.Config{InputFile: input, OutputFile: filepath.Join(dir, "openapi.yaml"), Check: true}
+	g, err := genspec.NewGenerator(config)
+	require.NoError(t, err)
+	err = g.Run()
+	var stale *genspec.StaleError
+	require.True(t, errors.As(err, &stale))
+	require.Equal(t, config.OutputFile+" is stale, regenerate it", err.Error())
+	require.Contains(t, stale.Diff, "+openapi: 3.0.0\n")
+}
@@ -0,0 +1,284 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// TypeMapper maps a Go type to its schema. The name is as written in the source,
+// "int64", "Money" or "decimal.Decimal". MapType returns nil for the types it does not map.
+type TypeMapper interface {
+	MapType(name string) *openapi3.Schema
+}
+
+type TypeMapperFunc func(name string) *openapi3.Schema
+
+func (f TypeMapperFunc) MapType(name string) *openapi3.Schema {
+	return f(name)
+}
+
+// SchemaMap is a TypeMapper by type names, returning copies of its schemas.
+type SchemaMap map[string]*openapi3.Schema
+
+func (m SchemaMap) MapType(name string) *openapi3.Schema {
+	s := m[name]
+	if s == nil {
+		return nil
+	}
+	// copy, tags must not modify the map.
+	c := *s
+	return &c
+}
+
+// DefaultTypes returns the mapping of the builtin and well-known types, used after Config.TypeMappers.
+// The unsigned types have minimum 0, and a format only when it holds their range.
+func DefaultTypes() SchemaMap {
+	return SchemaMap{
+		"int":     {Type: "integer"},
+		"int8":    {Type: "integer"},
+		"int16":   {Type: "integer"},
+		"int32":   {Type: "integer", Format: "int32"},
+		"int64":   {Type: "integer", Format: "int64"},
+		"uint":    {Type: "integer", Min: openapi3.Float64Ptr(0)},
+		"uint8":   {Type: "integer", Min: openapi3.Float64Ptr(0)},
+		"uint16":  {Type: "integer", Min: openapi3.Float64Ptr(0)},
+		"uint32":  {Type: "integer", Format: "int64", Min: openapi3.Float64Ptr(0)},
+		"uint64":  {Type: "integer", Min: openapi3.Float64Ptr(0)},
+		"byte":    {Type: "integer", Min: openapi3.Float64Ptr(0)},
+		"float32": {Type: "number", Format: "float"},
+		"float64": {Type: "number", Format: "double"},
+		"bool":    {Type: "boolean"},
+		"string":  {Type: "string"},
+
+		"time.Time":       {Type: "string", Format: "date-time"},
+		"time.Duration":   {Type: "integer", Format: "int64"},
+		"json.RawMessage": {},
+	}
+}
+
+// SchemaHook is given to the OnSchema hooks for each schema of components.
+// Changing Name renames the schema and the $refs to it.
+type SchemaHook struct {
+	Name   string
+	Schema *openapi3.SchemaRef
+	Pos    token.Position
+}
+
+// FieldHook is given to the OnField hooks for each property generated from a struct field.
+// Name, Schema and Required can be changed.
+type FieldHook struct {
+	Field    *ast.Field
+	Name     string
+	Schema   *openapi3.SchemaRef
+	Required bool
+	Pos      token.Position
+}
+
+// OperationHook is given to the OnOperation hooks for each operation generated from an interface method.
+type OperationHook struct {
+	Method    string
+	Path      string
+	Operation *openapi3.Operation
+	Pos       token.Position
+}
+
+// Hooks customize the generated spec. An error is reported at the position of the hook.
+type Hooks struct {
+	OnSchema    []func(*SchemaHook) error
+	OnField     []func(*FieldHook) error
+	OnOperation []func(*OperationHook) error
+}
+
+// AddTypeMapper adds m before the mappers added so far and DefaultTypes.
+func (g *Generator) AddTypeMapper(m TypeMapper) {
+	g.mappers = append([]TypeMapper{m}, g.mappers...)
+}
+
+func (g *Generator) OnSchema(fn func(*SchemaHook) error) {
+	g.hooks.OnSchema = append(g.hooks.OnSchema, fn)
+}
+
+func (g *Generator) OnField(fn func(*FieldHook) error) {
+	g.hooks.OnField = append(g.hooks.OnField, fn)
+}
+
+func (g *Generator) OnOperation(fn func(*OperationHook) error) {
+	g.hooks.OnOperation = append(g.hooks.OnOperation, fn)
+}
+
+func (g *Generator) mapType(name string) *openapi3.Schema {
+	for _, m := range g.mappers {
+		s := m.MapType(name)
+		if s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+func (g *Generator) fieldHooks(f *ast.Field, field *structField) {
+	if len(g.hooks.OnField) == 0 {
+		return
+	}
+	h := &FieldHook{
+		Field:    f,
+		Name:     field.name,
+		Schema:   field.schema,
+		Required: field.required,
+		Pos:      g.fset.Position(f.Pos()),
+	}
+	for _, fn := range g.hooks.OnField {
+		err := fn(h)
+		if err != nil {
+			g.errorf(f.Pos(), "%v", err)
+		}
+	}
+	field.name = h.Name
+	field.schema = h.Schema
+	field.required = h.Required
+}
+
+func (g *Generator) operationHooks(m *ast.Field, method, path string, ope *openapi3.Operation) {
+	h := &OperationHook{
+		Method:    method,
+		Path:      path,
+		Operation: ope,
+		Pos:       g.fset.Position(m.Pos()),
+	}
+	for _, fn := range g.hooks.OnOperation {
+		err := fn(h)
+		if err != nil {
+			g.errorf(m.Pos(), "%v", err)
+		}
+	}
+}
+
+// schemaHooks calls the OnSchema hooks for each schema of components, and renames the schemas.
+func (g *Generator) schemaHooks() {
+	if len(g.hooks.OnSchema) == 0 {
+		return
+	}
+	schemas := g.spec.Components.Schemas
+	renames := map[string]string{}
+	for _, name := range sortedSchemaNames(schemas) {
+		ptr := pointer("components", "schemas", name)
+		h := &SchemaHook{
+			Name:   name,
+			Schema: schemas[name],
+			Pos:    g.fset.Position(g.origin(ptr)),
+		}
+		for _, fn := range g.hooks.OnSchema {
+			err := fn(h)
+			if err != nil {
+				g.errorf(g.origin(ptr), "%v", err)
+			}
+		}
+		schemas[name] = h.Schema
+		if h.Name != name {
+			renames[name] = h.Name
+		}
+	}
+	taken := map[string]bool{}
+	for name := range schemas {
+		if _, ok := renames[name]; !ok {
+			taken[name] = true
+		}
+	}
+	names := make([]string, 0, len(renames))
+	for name := range renames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		to := renames[name]
+		if to == "" || taken[to] {
+			g.errorf(g.origin(pointer("components", "schemas", name)), "cannot rename %v to %q", name, to)
+			delete(renames, name)
+			taken[name] = true
+			continue
+		}
+		taken[to] = true
+	}
+	renamed := openapi3.Schemas{}
+	for name, s := range schemas {
+		to, ok := renames[name]
+		if !ok {
+			renamed[name] = s
+			continue
+		}
+		renamed[to] = s
+	}
+	g.spec.Components.Schemas = renamed
+	g.renamePointers(renames)
+	g.eachSchemaRef(func(r *openapi3.SchemaRef) {
+		to, ok := renames[strings.TrimPrefix(r.Ref, schemaRefPrefix)]
+		if ok && r.Ref != "" {
+			r.Ref = schemaRefPrefix + to
+		}
+		if r.Value != nil && r.Value.Discriminator != nil {
+			for k, v := range r.Value.Discriminator.Mapping {
+				to, ok := renames[strings.TrimPrefix(v, schemaRefPrefix)]
+				if ok {
+					r.Value.Discriminator.Mapping[k] = schemaRefPrefix + to
+				}
+			}
+		}
+	})
+}
+
+// eachSchemaRef calls fn for every schema of the spec, in components and operations.
+func (g *Generator) eachSchemaRef(fn func(*openapi3.SchemaRef)) {
+	c := g.spec.Components
+	for _, name := range sortedSchemaNames(c.Schemas) {
+		walkSchemaRefs(c.Schemas[name], fn)
+	}
+	walk := func(r *openapi3.SchemaRef) {
+		walkSchemaRefs(r, fn)
+	}
+	for _, p := range c.Parameters {
+		if p.Value != nil && p.Value.Schema != nil {
+			walk(p.Value.Schema)
+		}
+	}
+	for _, b := range c.RequestBodies {
+		if b.Value != nil {
+			for _, mt := range b.Value.Content {
+				if mt.Schema != nil {
+					walk(mt.Schema)
+				}
+			}
+		}
+	}
+	for _, res := range c.Responses {
+		if res.Value != nil {
+			for _, mt := range res.Value.Content {
+				if mt.Schema != nil {
+					walk(mt.Schema)
+				}
+			}
+		}
+	}
+	for _, path := range sortedPaths(g.spec.Paths) {
+		for _, ope := range g.spec.Paths[path].Operations() {
+			eachOperationSchema(ope, walk)
+		}
+	}
+}
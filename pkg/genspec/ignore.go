@@ -0,0 +1,97 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// IgnorePattern is the comment "//openapi:ignore rule[,rule...] [reason]" suppressing the lint rules,
+// for the whole spec in the package doc, or for a schema or an operation in the doc of its declaration.
+var IgnorePattern = regexp.MustCompile(`^//openapi:ignore\s+([\w-]+(?:,[\w-]+)*)`)
+
+// Pointer builds the JSON pointer of Position and Ignored, e.g. Pointer("paths", "/pets", "get").
+func Pointer(tokens ...string) string {
+	return pointer(tokens...)
+}
+
+// Position returns the position of the Go declaration that produced ptr, or its nearest parent.
+func (g *Generator) Position(ptr string) token.Position {
+	return g.fset.Position(g.origin(ptr))
+}
+
+// Ignored reports whether rule is suppressed for ptr by "//openapi:ignore" of ptr or its parents.
+func (g *Generator) Ignored(ptr, rule string) bool {
+	for {
+		for _, r := range g.ignores[ptr] {
+			if r == rule {
+				return true
+			}
+		}
+		i := strings.LastIndex(ptr, "/")
+		if i < 0 {
+			return false
+		}
+		ptr = ptr[:i]
+	}
+}
+
+// setIgnores records the rules of "//openapi:ignore" in docs for ptr.
+// The comment is a directive, doc.Text() and so the descriptions do not include it.
+func (g *Generator) setIgnores(ptr string, docs ...*ast.CommentGroup) {
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		for _, c := range doc.List {
+			m := IgnorePattern.FindStringSubmatch(c.Text)
+			if m != nil {
+				g.ignores[ptr] = append(g.ignores[ptr], strings.Split(m[1], ",")...)
+			}
+		}
+	}
+}
+
+// renamePointers moves the origins and the ignores of the renamed schemas, and of what is in them, to the new names.
+func (g *Generator) renamePointers(renames map[string]string) {
+	rename := func(ptr string) string {
+		for from, to := range renames {
+			old := pointer("components", "schemas", from)
+			if ptr == old || strings.HasPrefix(ptr, old+"/") {
+				return pointer("components", "schemas", to) + ptr[len(old):]
+			}
+		}
+		return ptr
+	}
+	origins := map[string]token.Pos{}
+	for ptr, pos := range g.origins {
+		origins[rename(ptr)] = pos
+	}
+	g.origins = origins
+	ignores := map[string][]string{}
+	for ptr, rules := range g.ignores {
+		ignores[rename(ptr)] = rules
+	}
+	g.ignores = ignores
+}
+
+// copyOrigin makes the schema to come from the declaration of the schema from, e.g. a renamed schema.
+func (g *Generator) copyOrigin(from, to string) {
+	g.setOrigin(pointer("components", "schemas", to), g.origin(pointer("components", "schemas", from)))
+	g.ignores[pointer("components", "schemas", to)] = g.ignores[pointer("components", "schemas", from)]
+}
@@ -0,0 +1,581 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Overlay is an OpenAPI Overlay 1.0 document, applied to the generated spec.
+//
+//	overlay: 1.0.0
+//	info: {title: gateway, version: 1.0.0}
+//	actions:
+//	- target: $.paths.*.*
+//	  update: {x-amazon-apigateway-integration: {type: http_proxy}}
+//	- target: $.paths['/internal']
+//	  remove: true
+//
+// The targets are a subset of JSONPath: $, .name, ['name'], [0], [*], .*, ..name
+// and the filters [?(@.key)], [?(@.key == 'value')] and [?(@.key != 'value')].
+type Overlay struct {
+	Version string
+	Title   string
+	Actions []*OverlayAction
+}
+
+type OverlayAction struct {
+	Target      string
+	Description string
+	Update      interface{}
+	Remove      bool
+}
+
+func LoadOverlay(file string) (*Overlay, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	o, err := ParseOverlay(data)
+	if err != nil {
+		return nil, errors.Wrap(err, file)
+	}
+	return o, nil
+}
+
+func ParseOverlay(data []byte) (*Overlay, error) {
+	ms := yaml.MapSlice{}
+	err := yaml.Unmarshal(data, &ms)
+	if err != nil {
+		return nil, err
+	}
+	o := &Overlay{}
+	o.Version, _ = mapSliceGet(ms, "overlay").(string)
+	if !strings.HasPrefix(o.Version, "1.") {
+		return nil, errors.Errorf("unsupported overlay version %q", o.Version)
+	}
+	info, _ := mapSliceGet(ms, "info").(yaml.MapSlice)
+	o.Title, _ = mapSliceGet(info, "title").(string)
+	actions, _ := mapSliceGet(ms, "actions").([]interface{})
+	if len(actions) == 0 {
+		return nil, errors.New("actions are required")
+	}
+	for i, v := range actions {
+		item, ok := v.(yaml.MapSlice)
+		if !ok {
+			return nil, errors.Errorf("action %d: must be an object", i)
+		}
+		a := &OverlayAction{}
+		a.Target, _ = mapSliceGet(item, "target").(string)
+		a.Description, _ = mapSliceGet(item, "description").(string)
+		a.Update = mapSliceGet(item, "update")
+		a.Remove, _ = mapSliceGet(item, "remove").(bool)
+		if a.Target == "" {
+			return nil, errors.Errorf("action %d: target is required", i)
+		}
+		if _, err := parsePath(a.Target); err != nil {
+			return nil, errors.Errorf("action %d: %v", i, err)
+		}
+		if a.Update == nil && !a.Remove {
+			return nil, errors.Errorf("action %d: update or remove is required", i)
+		}
+		o.Actions = append(o.Actions, a)
+	}
+	return o, nil
+}
+
+// applyOverlays applies the Overlays to the spec, resolves the $refs and validates the result again.
+// An action failing is an error, a target matching nothing is a warning.
+func (g *Generator) applyOverlays(ctx context.Context) error {
+	data, err := marshalYAML(g.spec)
+	if err != nil {
+		return err
+	}
+	doc, err := ParseMapSlice(string(data))
+	if err != nil {
+		return errors.Wrap(err, "ParseMapSlice")
+	}
+	for _, file := range g.config.Overlays {
+		o, err := LoadOverlay(file)
+		if err != nil {
+			return err
+		}
+		for i, a := range o.Actions {
+			var n int
+			*doc, n, err = a.Apply(*doc)
+			if err != nil {
+				return errors.Errorf("%v: action %d: %v", file, i, err)
+			}
+			if n == 0 {
+				g.diags = append(g.diags, Diagnostic{
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("%v: action %d: target %v matches nothing", file, i, a.Target),
+				})
+			}
+		}
+	}
+	data, err = yaml.Marshal(doc)
+	if err != nil {
+		return errors.Wrap(err, "yaml.Marshal")
+	}
+	var obj interface{}
+	err = yaml.Unmarshal(data, &obj)
+	if err == nil {
+		t := &openapi3.T{}
+		err = Convert(JSONValue(obj), t)
+		g.spec = t
+	}
+	if err != nil {
+		return errors.Wrap(err, "overlaid spec")
+	}
+	if HasError(g.diags) {
+		return nil
+	}
+	err = openapi3.NewLoader().ResolveRefsIn(g.spec, nil)
+	if err == nil {
+		err = g.spec.Validate(ctx)
+	}
+	if err != nil {
+		g.diags = append(g.diags, Diagnostic{
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("invalid spec after the overlays: %v", err),
+		})
+	}
+	return nil
+}
+
+// Apply applies the actions in order, the targets matching nothing are ignored.
+func (o *Overlay) Apply(doc yaml.MapSlice) (yaml.MapSlice, error) {
+	for i, a := range o.Actions {
+		var err error
+		doc, _, err = a.Apply(doc)
+		if err != nil {
+			return doc, errors.Errorf("action %d: %v", i, err)
+		}
+	}
+	return doc, nil
+}
+
+// Apply removes the target nodes, or merges the update into the target objects
+// (MergeMapSlice) and appends it to the target arrays. It returns the number of the targets.
+func (a *OverlayAction) Apply(doc yaml.MapSlice) (yaml.MapSlice, int, error) {
+	steps, err := parsePath(a.Target)
+	if err != nil {
+		return doc, 0, err
+	}
+	locs := selectPath(doc, steps)
+	var root interface{} = doc
+	// backwards, so removing does not move the indexes of the rest
+	for i := len(locs) - 1; i >= 0; i-- {
+		path := locs[i].path
+		if a.Remove {
+			if len(path) == 0 {
+				return doc, 0, errors.New("cannot remove the root")
+			}
+			root = removeIn(root, path)
+			continue
+		}
+		root, err = updateIn(root, path, func(v interface{}) (interface{}, error) {
+			return mergeValue(v, copyValue(a.Update))
+		})
+		if err != nil {
+			return doc, 0, errors.Errorf("%v: %v", formatPath(path), err)
+		}
+	}
+	return root.(yaml.MapSlice), len(locs), nil
+}
+
+func mergeValue(target, update interface{}) (interface{}, error) {
+	switch t := target.(type) {
+	case yaml.MapSlice:
+		u, ok := update.(yaml.MapSlice)
+		if !ok {
+			return nil, errors.New("update of an object must be an object")
+		}
+		MergeMapSlice(&t, &u)
+		return t, nil
+	case []interface{}:
+		return append(t, update), nil
+	}
+	return nil, errors.Errorf("cannot update %v", target)
+}
+
+func mapSliceGet(ms yaml.MapSlice, key string) interface{} {
+	for _, item := range ms {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+func CopyMapSlice(ms yaml.MapSlice) yaml.MapSlice {
+	return copyValue(ms).(yaml.MapSlice)
+}
+
+func copyValue(v interface{}) interface{} {
+	switch o := v.(type) {
+	case yaml.MapSlice:
+		ms := make(yaml.MapSlice, len(o))
+		for i, item := range o {
+			ms[i] = yaml.MapItem{Key: item.Key, Value: copyValue(item.Value)}
+		}
+		return ms
+	case []interface{}:
+		s := make([]interface{}, len(o))
+		for i, item := range o {
+			s[i] = copyValue(item)
+		}
+		return s
+	}
+	return v
+}
+
+type pathStepKind int
+
+const (
+	stepChild pathStepKind = iota
+	stepIndex
+	stepWildcard
+	stepFilter
+)
+
+type pathStep struct {
+	kind      pathStepKind
+	recursive bool
+	name      string
+	index     int
+	// filter: @.key op value
+	key   []*pathStep
+	op    string
+	value interface{}
+}
+
+// location is a node of the document and its path of keys and indexes.
+type location struct {
+	path  []interface{}
+	value interface{}
+}
+
+func parsePath(target string) ([]*pathStep, error) {
+	if !strings.HasPrefix(target, "$") {
+		return nil, errors.Errorf("invalid target %q: must start with $", target)
+	}
+	steps := []*pathStep{}
+	rest := target[1:]
+	for rest != "" {
+		recursive := false
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			recursive = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "["):
+		default:
+			return nil, errors.Errorf("invalid target %q at %q", target, rest)
+		}
+		var step *pathStep
+		var err error
+		if strings.HasPrefix(rest, "[") {
+			end := closingBracket(rest)
+			if end < 0 {
+				return nil, errors.Errorf("invalid target %q: unclosed [", target)
+			}
+			step, err = parseBracket(rest[1:end])
+			if err != nil {
+				return nil, errors.Errorf("invalid target %q: %v", target, err)
+			}
+			rest = rest[end+1:]
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+			switch name {
+			case "":
+				return nil, errors.Errorf("invalid target %q: empty name", target)
+			case "*":
+				step = &pathStep{kind: stepWildcard}
+			default:
+				step = &pathStep{kind: stepChild, name: name}
+			}
+		}
+		step.recursive = recursive
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// closingBracket returns the index of the ] closing s[0], skipping the quoted strings.
+func closingBracket(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseBracket(s string) (*pathStep, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "*":
+		return &pathStep{kind: stepWildcard}, nil
+	case strings.HasPrefix(s, "?"):
+		return parseFilter(s[1:])
+	case len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]:
+		return &pathStep{kind: stepChild, name: s[1 : len(s)-1]}, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, errors.Errorf("invalid selector [%v]", s)
+	}
+	return &pathStep{kind: stepIndex, index: i}, nil
+}
+
+// indexUnquoted returns the index of the first sub in s outside the quoted strings, or -1.
+func indexUnquoted(s, sub string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case strings.HasPrefix(s[i:], sub):
+			return i
+		}
+	}
+	return -1
+}
+
+func parseFilter(s string) (*pathStep, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	step := &pathStep{kind: stepFilter}
+	left := s
+	for _, op := range []string{"==", "!="} {
+		i := indexUnquoted(s, op)
+		if i < 0 {
+			continue
+		}
+		left = strings.TrimSpace(s[:i])
+		step.op = op
+		err := yaml.Unmarshal([]byte(s[i+len(op):]), &step.value)
+		if err != nil {
+			return nil, errors.Errorf("invalid filter value %v", s[i+len(op):])
+		}
+		break
+	}
+	if !strings.HasPrefix(left, "@") {
+		return nil, errors.Errorf("invalid filter %v: must start with @", s)
+	}
+	key, err := parsePath("$" + left[1:])
+	if err != nil {
+		return nil, errors.Errorf("invalid filter %v", s)
+	}
+	step.key = key
+	return step, nil
+}
+
+func selectPath(doc interface{}, steps []*pathStep) []*location {
+	locs := []*location{{path: []interface{}{}, value: doc}}
+	for _, step := range steps {
+		next := []*location{}
+		for _, loc := range locs {
+			if step.recursive {
+				for _, d := range descendants(loc) {
+					next = append(next, selectStep(d, step)...)
+				}
+			} else {
+				next = append(next, selectStep(loc, step)...)
+			}
+		}
+		locs = next
+	}
+	return locs
+}
+
+func selectStep(loc *location, step *pathStep) []*location {
+	locs := []*location{}
+	switch step.kind {
+	case stepChild:
+		ms, ok := loc.value.(yaml.MapSlice)
+		if !ok {
+			return locs
+		}
+		for _, item := range ms {
+			if fmt.Sprintf("%v", item.Key) == step.name {
+				locs = append(locs, child(loc, item.Key, item.Value))
+			}
+		}
+	case stepIndex:
+		s, ok := loc.value.([]interface{})
+		if !ok {
+			return locs
+		}
+		i := step.index
+		if i < 0 {
+			i += len(s)
+		}
+		if i >= 0 && i < len(s) {
+			locs = append(locs, child(loc, i, s[i]))
+		}
+	case stepWildcard:
+		locs = children(loc)
+	case stepFilter:
+		for _, c := range children(loc) {
+			if matchFilter(c.value, step) {
+				locs = append(locs, c)
+			}
+		}
+	}
+	return locs
+}
+
+func matchFilter(v interface{}, step *pathStep) bool {
+	found := selectPath(v, step.key)
+	if step.op == "" {
+		return len(found) > 0
+	}
+	equal := false
+	for _, f := range found {
+		if fmt.Sprintf("%v", f.value) == fmt.Sprintf("%v", step.value) {
+			equal = true
+		}
+	}
+	if step.op == "!=" {
+		return !equal
+	}
+	return equal
+}
+
+func child(loc *location, key, value interface{}) *location {
+	path := make([]interface{}, len(loc.path), len(loc.path)+1)
+	copy(path, loc.path)
+	return &location{path: append(path, key), value: value}
+}
+
+func children(loc *location) []*location {
+	locs := []*location{}
+	switch o := loc.value.(type) {
+	case yaml.MapSlice:
+		for _, item := range o {
+			locs = append(locs, child(loc, item.Key, item.Value))
+		}
+	case []interface{}:
+		for i, item := range o {
+			locs = append(locs, child(loc, i, item))
+		}
+	}
+	return locs
+}
+
+// descendants returns loc and all the nodes under it, in document order.
+func descendants(loc *location) []*location {
+	locs := []*location{loc}
+	for _, c := range children(loc) {
+		locs = append(locs, descendants(c)...)
+	}
+	return locs
+}
+
+func updateIn(v interface{}, path []interface{}, f func(interface{}) (interface{}, error)) (interface{}, error) {
+	if len(path) == 0 {
+		return f(v)
+	}
+	var err error
+	switch o := v.(type) {
+	case yaml.MapSlice:
+		for i := range o {
+			if o[i].Key == path[0] {
+				o[i].Value, err = updateIn(o[i].Value, path[1:], f)
+				break
+			}
+		}
+	case []interface{}:
+		i := path[0].(int)
+		o[i], err = updateIn(o[i], path[1:], f)
+	}
+	return v, err
+}
+
+func removeIn(v interface{}, path []interface{}) interface{} {
+	switch o := v.(type) {
+	case yaml.MapSlice:
+		for i := range o {
+			if o[i].Key != path[0] {
+				continue
+			}
+			if len(path) == 1 {
+				return append(o[:i:i], o[i+1:]...)
+			}
+			o[i].Value = removeIn(o[i].Value, path[1:])
+			break
+		}
+	case []interface{}:
+		i := path[0].(int)
+		if len(path) == 1 {
+			return append(o[:i:i], o[i+1:]...)
+		}
+		o[i] = removeIn(o[i], path[1:])
+	}
+	return v
+}
+
+func formatPath(path []interface{}) string {
+	b := strings.Builder{}
+	b.WriteString("$")
+	for _, p := range path {
+		switch k := p.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", k)
+		default:
+			fmt.Fprintf(&b, "[%q]", fmt.Sprintf("%v", k))
+		}
+	}
+	return b.String()
+}
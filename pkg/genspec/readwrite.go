@@ -0,0 +1,187 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	InputSuffix  = "Input"
+	OutputSuffix = "Output"
+)
+
+// splitReadWrite generates "PetInput" without the readOnly properties and "PetOutput" without the writeOnly ones,
+// for every schema that has them directly or through $ref,
+// and uses them in the request bodies and parameters, and in the responses.
+func (g *Generator) splitReadWrite() {
+	schemas := g.spec.Components.Schemas
+	names := sortedSchemaNames(schemas)
+
+	split := map[string]bool{}
+	for _, name := range names {
+		if hasAccessProperty(schemas[name]) {
+			split[name] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, name := range names {
+			if !split[name] && refersTo(schemas[name], split) {
+				split[name] = true
+				changed = true
+			}
+		}
+	}
+
+	// the $refs are replaced only with the variants created, not with a schema of the same name.
+	variants := map[bool]map[string]bool{true: {}, false: {}}
+	for _, name := range names {
+		if !split[name] {
+			continue
+		}
+		for _, input := range []bool{true, false} {
+			vname := variantName(name, input)
+			if schemas[vname] != nil {
+				g.errorf(g.origin(pointer("components", "schemas", name)), "%v already exists", vname)
+				continue
+			}
+			variants[input][name] = true
+		}
+	}
+	for _, name := range names {
+		for _, input := range []bool{true, false} {
+			if !variants[input][name] {
+				continue
+			}
+			v := &openapi3.SchemaRef{}
+			Convert(schemas[name], v)
+			stripAccess(v, variants[input], input)
+			vname := variantName(name, input)
+			schemas[vname] = v
+			g.copyOrigin(name, vname)
+		}
+	}
+
+	for _, path := range sortedPaths(g.spec.Paths) {
+		for _, ope := range g.spec.Paths[path].Operations() {
+			for _, p := range ope.Parameters {
+				if p.Value != nil && p.Value.Schema != nil {
+					stripAccess(p.Value.Schema, variants[true], true)
+				}
+			}
+			if ope.RequestBody != nil && ope.RequestBody.Value != nil {
+				for _, mt := range ope.RequestBody.Value.Content {
+					if mt.Schema != nil {
+						stripAccess(mt.Schema, variants[true], true)
+					}
+				}
+			}
+			for _, res := range ope.Responses {
+				if res.Value == nil {
+					continue
+				}
+				for _, mt := range res.Value.Content {
+					if mt.Schema != nil {
+						stripAccess(mt.Schema, variants[false], false)
+					}
+				}
+			}
+		}
+	}
+}
+
+func variantName(name string, input bool) string {
+	if input {
+		return name + InputSuffix
+	}
+	return name + OutputSuffix
+}
+
+func hasAccessProperty(ref *openapi3.SchemaRef) bool {
+	found := false
+	walkSchemaRefs(ref, func(r *openapi3.SchemaRef) {
+		if r.Value != nil && r.Ref == "" && (r.Value.ReadOnly || r.Value.WriteOnly) {
+			found = true
+		}
+	})
+	return found
+}
+
+func refersTo(ref *openapi3.SchemaRef, names map[string]bool) bool {
+	found := false
+	walkSchemaRefs(ref, func(r *openapi3.SchemaRef) {
+		if names[strings.TrimPrefix(r.Ref, schemaRefPrefix)] {
+			found = true
+		}
+	})
+	return found
+}
+
+// stripAccess removes the readOnly (input) or writeOnly (output) properties in place,
+// and replaces $refs to the schemas in variants with their variants.
+func stripAccess(ref *openapi3.SchemaRef, variants map[string]bool, input bool) {
+	if ref == nil {
+		return
+	}
+	if ref.Ref != "" {
+		name := strings.TrimPrefix(ref.Ref, schemaRefPrefix)
+		if variants[name] {
+			ref.Ref = schemaRefPrefix + variantName(name, input)
+			ref.Value = nil
+		}
+		return
+	}
+	s := ref.Value
+	if s == nil {
+		return
+	}
+	for name, p := range s.Properties {
+		if p.Value != nil && ((input && p.Value.ReadOnly) || (!input && p.Value.WriteOnly)) {
+			delete(s.Properties, name)
+			s.Required = removeString(s.Required, name)
+			continue
+		}
+		stripAccess(p, variants, input)
+	}
+	for _, refs := range []openapi3.SchemaRefs{s.AllOf, s.OneOf, s.AnyOf} {
+		for _, r := range refs {
+			stripAccess(r, variants, input)
+		}
+	}
+	stripAccess(s.Not, variants, input)
+	stripAccess(s.Items, variants, input)
+	stripAccess(s.AdditionalProperties, variants, input)
+	if s.Discriminator != nil {
+		for k, v := range s.Discriminator.Mapping {
+			name := strings.TrimPrefix(v, schemaRefPrefix)
+			if variants[name] {
+				s.Discriminator.Mapping[k] = schemaRefPrefix + variantName(name, input)
+			}
+		}
+	}
+}
+
+func removeString(src []string, s string) []string {
+	dst := []string{}
+	for _, v := range src {
+		if v != s {
+			dst = append(dst, v)
+		}
+	}
+	return dst
+}
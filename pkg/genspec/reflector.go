@@ -0,0 +1,472 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+// Reflector builds the spec from Go types at runtime, with the same tags as the generator:
+// json, scheme (or a JSON5 tag), validate (or binding), example and default.
+// Named types are put in components by their names.
+//
+//	r := genspec.NewReflector()
+//	err := r.AddOperation(&genspec.Operation{
+//		Method: "GET", Path: "/pets/{id}", OperationID: "FindPetByID",
+//		PathParams: struct{ ID int64 `json:"id"` }{},
+//		Responses:  map[string]*genspec.Response{"200": {Description: "pet response", Body: Pet{}}},
+//	})
+//	genspec.WriteTo(w, r.Spec(), genspec.FormatJSON)
+type Reflector struct {
+	// FlattenEmbedded promotes the fields of embedded structs like encoding/json, instead of allOf.
+	FlattenEmbedded bool
+	// TypeMappers map Go types to schemas before DefaultTypes. The names are reflect.Type.String(), "decimal.Decimal".
+	TypeMappers []TypeMapper
+
+	spec  *openapi3.T
+	types map[string]reflect.Type
+}
+
+// Operation declares an operation to the Reflector.
+type Operation struct {
+	Method      string
+	Path        string
+	OperationID string
+	Description string
+	Tags        []string
+	// PathParams is a struct of the path parameters, the others in Path are strings.
+	PathParams interface{}
+	// Params is a struct of the query parameters.
+	Params interface{}
+	// Body is the request body.
+	Body interface{}
+	// Responses by status code or "default".
+	Responses map[string]*Response
+}
+
+type Response struct {
+	Description string
+	// Body is the response body, none if nil.
+	Body interface{}
+}
+
+func NewReflector() *Reflector {
+	return &Reflector{
+		spec: &openapi3.T{
+			OpenAPI: "3.0.0",
+			Components: openapi3.Components{
+				Schemas: openapi3.Schemas{},
+			},
+			Paths: openapi3.Paths{},
+		},
+		types: map[string]reflect.Type{},
+	}
+}
+
+// Spec returns the spec built so far, set Info and the rest as needed.
+func (r *Reflector) Spec() *openapi3.T {
+	return r.spec
+}
+
+// Schema returns the schema of the type of v, a $ref for a named type.
+func (r *Reflector) Schema(v interface{}) (*openapi3.SchemaRef, error) {
+	return r.fromType(reflect.TypeOf(v))
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// AddOperation adds op to the paths of the spec.
+func (r *Reflector) AddOperation(op *Operation) error {
+	method := strings.ToUpper(op.Method)
+	if method == "" || !strings.HasPrefix(op.Path, "/") {
+		return errors.Errorf("invalid operation %v %v", op.Method, op.Path)
+	}
+	item := r.spec.Paths[op.Path]
+	if item != nil && item.GetOperation(method) != nil {
+		return errors.Errorf("%v %v is already added", method, op.Path)
+	}
+	if len(op.Responses) == 0 {
+		return errors.Errorf("%v %v: responses are required", method, op.Path)
+	}
+
+	ope := &openapi3.Operation{
+		OperationID: op.OperationID,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Parameters:  openapi3.Parameters{},
+		Responses:   openapi3.Responses{},
+	}
+	err := r.appendParams(ope, op)
+	if err != nil {
+		return errors.Errorf("%v %v: %v", method, op.Path, err)
+	}
+	if op.Body != nil {
+		schema, err := r.Schema(op.Body)
+		if err != nil {
+			return errors.Errorf("%v %v: body: %v", method, op.Path, err)
+		}
+		ope.RequestBody = &openapi3.RequestBodyRef{
+			Value: &openapi3.RequestBody{
+				Required: true,
+				Content:  openapi3.NewContentWithJSONSchemaRef(schema),
+			},
+		}
+	}
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if !isResCode(code) {
+			return errors.Errorf("%v %v: invalid response code %q", method, op.Path, code)
+		}
+		res := op.Responses[code]
+		desc := res.Description
+		value := &openapi3.Response{Description: &desc}
+		if res.Body != nil {
+			schema, err := r.Schema(res.Body)
+			if err != nil {
+				return errors.Errorf("%v %v: response %v: %v", method, op.Path, code, err)
+			}
+			value.Content = openapi3.NewContentWithJSONSchemaRef(schema)
+		}
+		ope.Responses[code] = &openapi3.ResponseRef{Value: value}
+	}
+	if item == nil {
+		item = &openapi3.PathItem{}
+		r.spec.Paths[op.Path] = item
+	}
+	item.SetOperation(method, ope)
+	return nil
+}
+
+func (r *Reflector) appendParams(ope *openapi3.Operation, op *Operation) error {
+	pathParams := []*structField{}
+	if op.PathParams != nil {
+		fields, err := r.paramFields(op.PathParams)
+		if err != nil {
+			return errors.Wrap(err, "path params")
+		}
+		pathParams = fields
+	}
+	for _, m := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+		schema := ref(&openapi3.Schema{Type: "string"})
+		for _, f := range pathParams {
+			if f.name == m[1] {
+				schema = f.schema
+			}
+		}
+		ope.Parameters = append(ope.Parameters, &openapi3.ParameterRef{
+			Value: &openapi3.Parameter{
+				Name:     m[1],
+				In:       "path",
+				Required: true,
+				Schema:   schema,
+			},
+		})
+	}
+	for _, f := range pathParams {
+		if ope.Parameters.GetByInAndName("path", f.name) == nil {
+			return errors.Errorf("path param %v is not in the path", f.name)
+		}
+	}
+
+	if op.Params == nil {
+		return nil
+	}
+	fields, err := r.paramFields(op.Params)
+	if err != nil {
+		return errors.Wrap(err, "params")
+	}
+	for _, f := range fields {
+		var example interface{}
+		if f.schema.Value != nil {
+			example = f.schema.Value.Example
+		}
+		ope.Parameters = append(ope.Parameters, &openapi3.ParameterRef{
+			Value: &openapi3.Parameter{
+				Name:     f.name,
+				In:       "query",
+				Required: f.validated && f.required,
+				Schema:   f.schema,
+				Example:  example,
+			},
+		})
+	}
+	return nil
+}
+
+func (r *Reflector) paramFields(v interface{}) ([]*structField, error) {
+	t := derefType(reflect.TypeOf(v))
+	if t.Kind() != reflect.Struct {
+		return nil, errors.Errorf("must be a struct: %v", t)
+	}
+	fields, _, err := r.structFields(t, true, map[reflect.Type]bool{t: true})
+	if err != nil {
+		return nil, err
+	}
+	return dominantFields(fields), nil
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func (r *Reflector) mapType(t reflect.Type) *openapi3.Schema {
+	for _, m := range append(append([]TypeMapper{}, r.TypeMappers...), DefaultTypes()) {
+		s := m.MapType(t.String())
+		if s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+func (r *Reflector) fromType(t reflect.Type) (*openapi3.SchemaRef, error) {
+	if t == nil {
+		return ref(&openapi3.Schema{}), nil
+	}
+	t = derefType(t)
+	s := r.mapType(t)
+	if s != nil {
+		return ref(s), nil
+	}
+	if t.Name() != "" && t.PkgPath() != "" {
+		return r.fromNamedType(t)
+	}
+	return r.fromUnderlyingType(t)
+}
+
+// fromNamedType puts the schema of a named type in components, and refers to it.
+func (r *Reflector) fromNamedType(t reflect.Type) (*openapi3.SchemaRef, error) {
+	name := t.Name()
+	prev, ok := r.types[name]
+	if ok {
+		if prev != t {
+			return nil, errors.Errorf("schema %v of %v is already used by %v", name, t.PkgPath(), prev.PkgPath())
+		}
+		return &openapi3.SchemaRef{Ref: schemaRefPrefix + name}, nil
+	}
+	// before the schema, for the recursive types.
+	r.types[name] = t
+	schema, err := r.fromUnderlyingType(t)
+	if err != nil {
+		delete(r.types, name)
+		return nil, err
+	}
+	r.spec.Components.Schemas[name] = schema
+	return &openapi3.SchemaRef{Ref: schemaRefPrefix + name}, nil
+}
+
+func (r *Reflector) fromUnderlyingType(t reflect.Type) (*openapi3.SchemaRef, error) {
+	switch t.Kind() {
+	case reflect.Struct:
+		return r.fromStruct(t)
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return ref(&openapi3.Schema{Type: "string", Format: "byte"}), nil
+		}
+		items, err := r.fromType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return ref(&openapi3.Schema{Type: "array", Items: items}), nil
+	case reflect.Map:
+		value, err := r.fromType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return ref(&openapi3.Schema{Type: "object", AdditionalProperties: value}), nil
+	case reflect.Interface:
+		// any value
+		return ref(&openapi3.Schema{}), nil
+	}
+	s := DefaultTypes().MapType(t.Kind().String())
+	if s == nil {
+		return nil, errors.Errorf("unsupported type %v", t)
+	}
+	return ref(s), nil
+}
+
+func (r *Reflector) fromStruct(t reflect.Type) (*openapi3.SchemaRef, error) {
+	fields, parents, err := r.structFields(t, r.FlattenEmbedded, map[reflect.Type]bool{t: true})
+	if err != nil {
+		return nil, err
+	}
+	schema := objectSchema(dominantFields(fields))
+	if len(parents) == 0 {
+		return ref(schema), nil
+	}
+
+	allOf := openapi3.SchemaRefs{}
+	for _, p := range parents {
+		allOf = append(allOf, &openapi3.SchemaRef{Ref: schemaRefPrefix + p})
+	}
+	allOf = append(allOf, ref(schema))
+	return ref(&openapi3.Schema{
+		AllOf: allOf,
+	}), nil
+}
+
+// structFields returns the fields of t in declaration order, and the embedded types that are not flattened.
+func (r *Reflector) structFields(t reflect.Type, flatten bool, visiting map[reflect.Type]bool) ([]*structField, []string, error) {
+	parents := []string{}
+	fields := []*structField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := tagJSONName(f.Tag)
+		if name == "-" {
+			continue
+		}
+		ft := derefType(f.Type)
+		// a mapped type like time.Time is a field named by the type, not a parent.
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct && r.mapType(ft) == nil {
+			if flatten {
+				if visiting[ft] {
+					return nil, nil, errors.Errorf("embedding cycle on %v", ft.Name())
+				}
+				visiting[ft] = true
+				embedded, _, err := r.structFields(ft, true, visiting)
+				delete(visiting, ft)
+				if err != nil {
+					return nil, nil, err
+				}
+				for _, sf := range embedded {
+					sf.depth++
+				}
+				fields = append(fields, embedded...)
+				continue
+			}
+			_, err := r.fromType(ft)
+			if err != nil {
+				return nil, nil, err
+			}
+			parents = append(parents, ft.Name())
+			continue
+		}
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+		field, err := r.fromField(f, name)
+		if err != nil {
+			return nil, nil, errors.Errorf("%v.%v: %v", t.Name(), f.Name, err)
+		}
+		fields = append(fields, field)
+	}
+	return fields, parents, nil
+}
+
+func (r *Reflector) fromField(f reflect.StructField, name string) (*structField, error) {
+	if name == "" {
+		name = fieldName(f.Name)
+	}
+	tag := string(f.Tag)
+	kv, err := ParseSchemeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	readOnly := kv["readOnly"] == true
+	writeOnly := kv["writeOnly"] == true
+	if readOnly && writeOnly {
+		return nil, errors.New("field cannot be both readOnly and writeOnly")
+	}
+
+	// without validate tag, all fields are required.
+	required := true
+	var vt *ValidateTag
+	if tag != "" && !strings.HasPrefix(strings.TrimSpace(tag), "{") {
+		v := GetValidateTag(tag)
+		if v != "" {
+			vt = ParseValidateTag(v)
+			required = vt.Required && !vt.OmitEmpty
+		}
+	}
+
+	prop, err := r.fromType(f.Type)
+	if err != nil {
+		return nil, err
+	}
+	if prop.Value != nil {
+		err = ExpandValidateTagForScheme(prop.Value, vt)
+		if err != nil {
+			return nil, errors.Wrap(err, "validate tag")
+		}
+		if len(kv) > 0 {
+			err = ExpandTagForScheme(prop.Value, kv)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	prop, err = r.setExampleFromTag(f.Tag, prop)
+	if err != nil {
+		return nil, err
+	}
+	return &structField{
+		name:      name,
+		schema:    withAccess(prop, readOnly, writeOnly),
+		required:  required,
+		validated: vt != nil,
+		readOnly:  readOnly,
+		writeOnly: writeOnly,
+	}, nil
+}
+
+// setExampleFromTag sets the example and default tags to prop, typed by its schema.
+func (r *Reflector) setExampleFromTag(tag reflect.StructTag, prop *openapi3.SchemaRef) (*openapi3.SchemaRef, error) {
+	example, hasExample := tag.Lookup("example")
+	def, hasDefault := tag.Lookup("default")
+	if !hasExample && !hasDefault {
+		return prop, nil
+	}
+
+	typed := prop.Value
+	if prop.Ref != "" {
+		typed = &openapi3.Schema{}
+		s := r.spec.Components.Schemas[strings.TrimPrefix(prop.Ref, schemaRefPrefix)]
+		if s != nil && s.Value != nil {
+			typed = s.Value
+		}
+		prop = ref(&openapi3.Schema{
+			AllOf: openapi3.SchemaRefs{prop},
+		})
+	}
+	if hasExample {
+		v, err := TypedValue(typed, example)
+		if err != nil {
+			return nil, errors.Wrap(err, "example")
+		}
+		prop.Value.Example = v
+	}
+	if hasDefault {
+		v, err := TypedValue(typed, def)
+		if err != nil {
+			return nil, errors.Wrap(err, "default")
+		}
+		prop.Value.Default = v
+	}
+	return prop, nil
+}
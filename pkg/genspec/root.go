@@ -0,0 +1,242 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"reflect"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v2"
+)
+
+// The root of the spec is built from these declarations:
+//
+//	OpenAPISpec   the base document; info, servers, tags, externalDocs, security and x- extensions survive,
+//	              openapi and paths are always generated, components are merged with the generated ones.
+//	Servers       servers, a list of URLs or Server objects with variables.
+//	Tags          tags, in the order of the declaration.
+//	ExternalDocs  externalDocs, a URL or an ExternalDocs object.
+//	Auth          components.securitySchemes and security.
+//
+// A dedicated declaration wins over the same part of OpenAPISpec, with a warning.
+// A component both written in OpenAPISpec and generated, e.g. a schema of the same name, is merged key by key;
+// a value that differs between them is an error.
+
+// unmarshalValue unmarshals the YAML string of vs into dst, reporting the errors at vs.
+func (g *Generator) unmarshalValue(vs *ast.ValueSpec, dst interface{}) bool {
+	name := vs.Names[0].Name
+	text, ok := getBasicLitValue(vs)
+	if !ok {
+		g.errorf(vs.Pos(), "%v must be a string constant", name)
+		return false
+	}
+	var obj interface{}
+	err := yaml.Unmarshal([]byte(text), &obj)
+	if err != nil {
+		g.errorf(vs.Pos(), "invalid %v: %v", name, err)
+		return false
+	}
+	err = Convert(JSONValue(obj), dst)
+	if err != nil {
+		g.errorf(vs.Pos(), "invalid %v: %v", name, err)
+		return false
+	}
+	return true
+}
+
+func (g *Generator) fromServers(vs *ast.ValueSpec) {
+	items := []interface{}{}
+	if !g.unmarshalValue(vs, &items) {
+		return
+	}
+	servers := openapi3.Servers{}
+	for i, item := range items {
+		url, ok := item.(string)
+		if ok {
+			item = map[string]interface{}{"url": url}
+		}
+		server := &openapi3.Server{}
+		err := Convert(item, server)
+		if err == nil {
+			err = server.Validate(context.Background())
+		}
+		if err != nil {
+			g.errorf(vs.Pos(), "invalid server %v: %v", i, err)
+			continue
+		}
+		servers = append(servers, server)
+	}
+	if len(g.spec.Servers) > 0 {
+		g.warnf(vs.Pos(), "Servers overrides the servers of OpenAPISpec")
+	}
+	g.spec.Servers = servers
+	g.setOrigin(pointer("servers"), vs.Pos())
+}
+
+func (g *Generator) fromTags(vs *ast.ValueSpec) {
+	items := []interface{}{}
+	if !g.unmarshalValue(vs, &items) {
+		return
+	}
+	tags := openapi3.Tags{}
+	for i, item := range items {
+		name, ok := item.(string)
+		if ok {
+			item = map[string]interface{}{"name": name}
+		}
+		tag := &openapi3.Tag{}
+		err := Convert(item, tag)
+		if err == nil && tag.Name == "" {
+			err = fmt.Errorf("name is required")
+		}
+		if err != nil {
+			g.errorf(vs.Pos(), "invalid tag %v: %v", i, err)
+			continue
+		}
+		if tags.Get(tag.Name) != nil {
+			g.errorf(vs.Pos(), "duplicate tag %q", tag.Name)
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	if len(g.spec.Tags) > 0 {
+		g.warnf(vs.Pos(), "Tags overrides the tags of OpenAPISpec")
+	}
+	g.spec.Tags = tags
+	g.tagsPos = vs.Pos()
+	g.setOrigin(pointer("tags"), vs.Pos())
+}
+
+func (g *Generator) fromExternalDocs(vs *ast.ValueSpec) {
+	var item interface{}
+	if !g.unmarshalValue(vs, &item) {
+		return
+	}
+	url, ok := item.(string)
+	if ok {
+		item = map[string]interface{}{"url": url}
+	}
+	docs := &openapi3.ExternalDocs{}
+	err := Convert(item, docs)
+	if err == nil && docs.URL == "" {
+		err = fmt.Errorf("url is required")
+	}
+	if err != nil {
+		g.errorf(vs.Pos(), "invalid ExternalDocs: %v", err)
+		return
+	}
+	if g.spec.ExternalDocs != nil {
+		g.warnf(vs.Pos(), "ExternalDocs overrides the externalDocs of OpenAPISpec")
+	}
+	g.spec.ExternalDocs = docs
+	g.setOrigin(pointer("externalDocs"), vs.Pos())
+}
+
+// setOperationTags sets the tags of the operation doc, which must be declared by Tags if any.
+func (g *Generator) setOperationTags(m *ast.Field, ope *openapi3.Operation, tags interface{}) {
+	switch t := tags.(type) {
+	case string:
+		ope.Tags = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			ope.Tags = append(ope.Tags, fmt.Sprintf("%v", v))
+		}
+	default:
+		g.errorf(m.Pos(), "tags must be a string or a list: %v", tags)
+		return
+	}
+	if !g.tagsPos.IsValid() {
+		return
+	}
+	for _, tag := range ope.Tags {
+		if g.spec.Tags.Get(tag) == nil {
+			g.warnf(m.Pos(), "tag %q is not declared in Tags", tag)
+		}
+	}
+}
+
+// mergeComponents merges the components written in OpenAPISpec into the generated ones.
+func (g *Generator) mergeComponents() {
+	if g.specComponents == nil {
+		return
+	}
+	merged := map[string]interface{}{}
+	Convert(g.spec.Components, &merged)
+	declared := map[string]interface{}{}
+	Convert(g.specComponents, &declared)
+	for _, kind := range sortedTagKeys(declared) {
+		items, ok := declared[kind].(map[string]interface{})
+		if !ok || isExtension(kind) {
+			merged[kind] = declared[kind]
+			continue
+		}
+		generated, ok := merged[kind].(map[string]interface{})
+		if !ok {
+			generated = map[string]interface{}{}
+			merged[kind] = generated
+		}
+		for _, name := range sortedTagKeys(items) {
+			g.mergeComponent(kind, name, nil, generated, items)
+		}
+	}
+	components := openapi3.NewComponents()
+	err := Convert(merged, &components)
+	if err != nil {
+		g.errorf(g.specPos, "invalid components of OpenAPISpec: %v", err)
+		return
+	}
+	g.spec.Components = components
+}
+
+// mergeComponent merges the key at path of the component declared in OpenAPISpec into the generated one,
+// the objects of both are merged like MergeMapSlice and the other values must be the same.
+func (g *Generator) mergeComponent(kind, name string, path []string, generated, declared map[string]interface{}) {
+	key := name
+	if len(path) > 0 {
+		key = path[len(path)-1]
+	}
+	ptr := pointer(append([]string{"components", kind, name}, path...)...)
+	value, ok := generated[key]
+	if !ok {
+		generated[key] = declared[key]
+		g.setOrigin(ptr, g.specPos)
+		return
+	}
+	a, okA := value.(map[string]interface{})
+	b, okB := declared[key].(map[string]interface{})
+	if okA && okB {
+		for _, k := range sortedTagKeys(b) {
+			g.mergeComponent(kind, name, append(append([]string{}, path...), k), a, b)
+		}
+		return
+	}
+	if reflect.DeepEqual(value, declared[key]) {
+		return
+	}
+	where := "generated"
+	pos := g.origin(ptr)
+	if pos.IsValid() {
+		where = fmt.Sprintf("generated at %v", g.fset.Position(pos))
+	}
+	if len(path) == 0 {
+		g.errorf(g.specPos, "%v %q of OpenAPISpec is also %v", kind, name, where)
+		return
+	}
+	g.errorf(g.specPos, "%v %q of OpenAPISpec is also %v, %v differs", kind, name, where, strings.Join(path, "."))
+}
@@ -0,0 +1,337 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"go/ast"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/iancoleman/strcase"
+)
+
+type structField struct {
+	name      string
+	schema    *openapi3.SchemaRef
+	required  bool
+	readOnly  bool
+	writeOnly bool
+	depth     int
+	// validated is true when required is set by a validate tag.
+	validated bool
+}
+
+// embeddedName returns the type name of an embedded field: Base, *Base, pkg.Base or *pkg.Base.
+func embeddedName(expr ast.Expr) (string, bool) {
+	star, ok := expr.(*ast.StarExpr)
+	if ok {
+		expr = star.X
+	}
+	switch i := expr.(type) {
+	case *ast.Ident:
+		return i.Name, true
+	case *ast.SelectorExpr:
+		return i.Sel.Name, true
+	}
+	return "", false
+}
+
+// jsonName returns the name from the json tag, "-" when the field is skipped.
+func jsonName(f *ast.Field) string {
+	if f.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return tagJSONName(reflect.StructTag(tag))
+}
+
+func tagJSONName(tag reflect.StructTag) string {
+	name := tag.Get("json")
+	i := strings.Index(name, ",")
+	if i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// fieldName returns the property name of a Go field without json tag.
+func fieldName(name string) string {
+	return strcase.ToLowerCamel(name)
+}
+
+func (g *Generator) fromStruct(s *ast.StructType) *openapi3.SchemaRef {
+	fields, parents := g.structFields(s, g.config.FlattenEmbedded, map[string]bool{})
+
+	schema := objectSchema(dominantFields(fields))
+	if len(parents) == 0 {
+		return ref(schema)
+	}
+
+	allOf := openapi3.SchemaRefs{}
+	for _, p := range parents {
+		allOf = append(allOf, &openapi3.SchemaRef{Ref: schemaRefPrefix + p})
+	}
+	allOf = append(allOf, ref(schema))
+	return ref(&openapi3.Schema{
+		AllOf: allOf,
+	})
+}
+
+// structFields returns the fields of s in declaration order, and the embedded types that are not flattened.
+func (g *Generator) structFields(s *ast.StructType, flatten bool, visiting map[string]bool) ([]*structField, []string) {
+	parents := []string{}
+	fields := []*structField{}
+
+	for _, f := range s.Fields.List {
+		if jsonName(f) == "-" {
+			continue
+		}
+		if len(f.Names) == 0 && jsonName(f) == "" && !g.mappedEmbedded(f.Type) {
+			name, ok := embeddedName(f.Type)
+			if !ok {
+				g.errorf(f.Pos(), "cannot embed %T", f.Type)
+				continue
+			}
+			if flatten {
+				embedded, ok := g.embeddedFields(f, name, visiting)
+				if ok {
+					fields = append(fields, embedded...)
+					continue
+				}
+			}
+			parents = append(parents, name)
+			continue
+		}
+		fields = append(fields, g.fromField(f)...)
+	}
+	return fields, parents
+}
+
+// mappedEmbedded reports whether an embedded type is mapped by the TypeMappers, like time.Time.
+// Such a type is a field named by the type, not a parent.
+func (g *Generator) mappedEmbedded(expr ast.Expr) bool {
+	star, ok := expr.(*ast.StarExpr)
+	if ok {
+		expr = star.X
+	}
+	switch i := expr.(type) {
+	case *ast.Ident:
+		return g.mapType(i.Name) != nil
+	case *ast.SelectorExpr:
+		x, ok := i.X.(*ast.Ident)
+		return ok && g.mapType(x.Name+"."+i.Sel.Name) != nil
+	}
+	return false
+}
+
+func (g *Generator) fromField(f *ast.Field) []*structField {
+	names := []string{}
+	name := jsonName(f)
+	if name != "" {
+		names = append(names, name)
+	} else if len(f.Names) == 0 {
+		n, _ := embeddedName(f.Type)
+		names = append(names, fieldName(n))
+	} else {
+		for _, n := range f.Names {
+			names = append(names, fieldName(n.Name))
+		}
+	}
+
+	tag := ""
+	if f.Tag != nil {
+		tag, _ = strconv.Unquote(f.Tag.Value)
+	}
+	kv := g.parseTag(f, tag)
+	readOnly, writeOnly := g.fieldAccess(f, kv)
+
+	// without validate tag, all fields are required.
+	required := true
+	var vt *ValidateTag
+	if tag != "" && !strings.HasPrefix(strings.TrimSpace(tag), "{") {
+		v := GetValidateTag(tag)
+		if v != "" {
+			vt = ParseValidateTag(v)
+			required = vt.Required && !vt.OmitEmpty
+		}
+	}
+
+	fields := []*structField{}
+	for i, n := range names {
+		prop := g.fromType(f.Type)
+		if prop.Value != nil {
+			err := ExpandValidateTagForScheme(prop.Value, vt)
+			if err != nil && i == 0 {
+				g.errorf(f.Tag.Pos(), "validate tag: %v", err)
+			}
+		}
+		if prop.Value != nil && len(kv) > 0 {
+			err := ExpandTagForScheme(prop.Value, kv)
+			if err != nil && i == 0 {
+				g.errorf(f.Tag.Pos(), "%v", err)
+			}
+		}
+		if tag != "" {
+			prop = g.setExampleFromTag(f, tag, prop)
+		}
+		prop, err := withExtensions(prop, g.extensionsDirective(f.Doc, f.Comment))
+		if err != nil && i == 0 {
+			g.errorf(f.Pos(), "(extensions): %v", err)
+		}
+		field := &structField{
+			name:      n,
+			schema:    withAccess(prop, readOnly, writeOnly),
+			required:  required,
+			validated: vt != nil,
+			readOnly:  readOnly,
+			writeOnly: writeOnly,
+		}
+		g.fieldHooks(f, field)
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// parseTag parses the JSON5 of the tag, reporting errors at their position in the tag.
+func (g *Generator) parseTag(f *ast.Field, tag string) KeyValue {
+	if f.Tag == nil {
+		return KeyValue{}
+	}
+	kv, err := ParseSchemeTag(tag)
+	if err != nil {
+		pos := f.Tag.Pos()
+		te, ok := err.(*TagError)
+		if ok && strings.HasPrefix(f.Tag.Value, "`") {
+			// the offset is exact only in a raw string.
+			pos += token.Pos(1 + te.Offset)
+		}
+		g.errorf(pos, "%v", err)
+		return kv
+	}
+	if g.config.StrictTags {
+		err = CheckTagKeys(kv)
+		if err != nil {
+			g.errorf(f.Tag.Pos(), "%v", err)
+		}
+	}
+	return kv
+}
+
+// fieldAccess returns readOnly and writeOnly of a field,
+// set by the tag {readOnly:true} or by the directive "(readOnly)" in its comment.
+func (g *Generator) fieldAccess(f *ast.Field, kv KeyValue) (bool, bool) {
+	readOnly := kv["readOnly"] == true
+	writeOnly := kv["writeOnly"] == true
+	for _, doc := range []*ast.CommentGroup{f.Doc, f.Comment} {
+		_, directives := g.parseDirectives(doc)
+		if findDirective(directives, "readOnly") != nil {
+			readOnly = true
+		}
+		if findDirective(directives, "writeOnly") != nil {
+			writeOnly = true
+		}
+	}
+	if readOnly && writeOnly {
+		g.errorf(f.Pos(), "field cannot be both readOnly and writeOnly")
+	}
+	return readOnly, writeOnly
+}
+
+// withAccess sets readOnly/writeOnly, wrapping a $ref by allOf as siblings of $ref are ignored.
+func withAccess(prop *openapi3.SchemaRef, readOnly, writeOnly bool) *openapi3.SchemaRef {
+	if !readOnly && !writeOnly {
+		return prop
+	}
+	if prop.Ref != "" {
+		prop = ref(&openapi3.Schema{
+			AllOf: openapi3.SchemaRefs{prop},
+		})
+	}
+	prop.Value.ReadOnly = readOnly
+	prop.Value.WriteOnly = writeOnly
+	return prop
+}
+
+// embeddedFields returns the fields promoted from an embedded struct, like encoding/json.
+func (g *Generator) embeddedFields(f *ast.Field, name string, visiting map[string]bool) ([]*structField, bool) {
+	ts := g.types[name]
+	if ts == nil {
+		g.warnf(f.Pos(), "cannot flatten %v, not declared in this file", name)
+		return nil, false
+	}
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		g.warnf(f.Pos(), "cannot flatten %v, not a struct", name)
+		return nil, false
+	}
+	if visiting[name] {
+		g.errorf(f.Pos(), "embedding cycle on %v", name)
+		return nil, true
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	fields, _ := g.structFields(st, true, visiting)
+	for _, sf := range fields {
+		sf.depth++
+	}
+	return fields, true
+}
+
+// dominantFields drops the fields hidden by a shallower field of the same name,
+// and the ambiguous ones that have the same name at the same depth.
+func dominantFields(fields []*structField) []*structField {
+	depth := map[string]int{}
+	count := map[string]int{}
+	for _, f := range fields {
+		d, ok := depth[f.name]
+		if !ok || f.depth < d {
+			depth[f.name] = f.depth
+			count[f.name] = 1
+		} else if f.depth == d {
+			count[f.name]++
+		}
+	}
+
+	dst := []*structField{}
+	for _, f := range fields {
+		if f.depth == depth[f.name] && count[f.name] == 1 {
+			dst = append(dst, f)
+		}
+	}
+	return dst
+}
+
+func objectSchema(fields []*structField) *openapi3.Schema {
+	required := []string{}
+	properties := openapi3.Schemas{}
+	for _, f := range fields {
+		if f.required {
+			required = append(required, f.name)
+		}
+		properties[f.name] = f.schema
+	}
+	return &openapi3.Schema{
+		Type:       "object",
+		Properties: properties,
+		Required:   required,
+	}
+}
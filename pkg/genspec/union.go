@@ -0,0 +1,141 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/iancoleman/strcase"
+)
+
+// collectImplementers collects the receiver types of methods, grouped by method name.
+func (g *Generator) collectImplementers(af *ast.File) {
+	for _, d := range af.Decls {
+		fd, ok := d.(*ast.FuncDecl)
+		if !ok || fd.Recv == nil || len(fd.Recv.List) != 1 {
+			continue
+		}
+		typ := fd.Recv.List[0].Type
+		star, ok := typ.(*ast.StarExpr)
+		if ok {
+			typ = star.X
+		}
+		i, ok := typ.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		name := fd.Name.Name
+		g.implementers[name] = append(g.implementers[name], i.Name)
+	}
+}
+
+func isMarkerMethod(m *ast.Field) bool {
+	if len(m.Names) != 1 || ast.IsExported(m.Names[0].Name) {
+		return false
+	}
+	ft, ok := m.Type.(*ast.FuncType)
+	if !ok {
+		return false
+	}
+	return ft.Params.NumFields() == 0 && ft.Results.NumFields() == 0
+}
+
+// isUnion reports whether i only has marker methods, e.g. "isShape()".
+func isUnion(i *ast.InterfaceType) bool {
+	if len(i.Methods.List) == 0 {
+		return false
+	}
+	for _, m := range i.Methods.List {
+		if !isMarkerMethod(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// generateUnion generates oneOf/anyOf from an interface with marker methods
+// or from a "(oneOf kind)" / "(anyOf kind)" directive.
+//
+//	// (oneOf kind)
+//	// circle: Circle
+//	// square: Square
+//	type Shape interface {
+//		isShape()
+//	}
+//
+// Without mapping, members are the types implementing the marker method.
+func (g *Generator) generateUnion(ts *ast.TypeSpec, i *ast.InterfaceType, d *Directive) {
+	name := ts.Name.Name
+	kind := "oneOf"
+	discriminator := ""
+	if d != nil {
+		kind = d.Name
+		if len(d.Args) > 0 {
+			discriminator = d.Args[0]
+		}
+	}
+
+	keys := []string{}
+	members := map[string]string{}
+	if d != nil && len(d.KV) > 0 {
+		for k, v := range d.KV {
+			keys = append(keys, k)
+			members[k] = fmt.Sprintf("%v", v)
+		}
+		sort.Strings(keys)
+	} else {
+		for _, m := range i.Methods.List {
+			for _, t := range g.implementers[m.Names[0].Name] {
+				k := strcase.ToLowerCamel(t)
+				if _, ok := members[k]; ok {
+					continue
+				}
+				keys = append(keys, k)
+				members[k] = t
+			}
+		}
+	}
+	if len(keys) == 0 {
+		g.errorf(ts.Pos(), "%v %v has no members", kind, name)
+		return
+	}
+
+	refs := openapi3.SchemaRefs{}
+	mapping := map[string]string{}
+	for _, k := range keys {
+		r := schemaRefPrefix + members[k]
+		refs = append(refs, &openapi3.SchemaRef{Ref: r})
+		mapping[k] = r
+	}
+
+	schema := &openapi3.Schema{}
+	switch kind {
+	case "oneOf":
+		schema.OneOf = refs
+	case "anyOf":
+		schema.AnyOf = refs
+	}
+	if discriminator != "" {
+		schema.Discriminator = &openapi3.Discriminator{
+			PropertyName: discriminator,
+			Mapping:      mapping,
+		}
+	}
+	g.spec.Components.Schemas[name] = ref(schema)
+	g.setOrigin(pointer("components", "schemas", name), ts.Pos())
+}
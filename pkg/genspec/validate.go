@@ -0,0 +1,297 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"context"
+	"encoding/json"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const schemaRefPrefix = "#/components/schemas/"
+
+// pointer builds a JSON pointer (RFC 6901) used as the key of Generator.origins.
+func pointer(tokens ...string) string {
+	b := strings.Builder{}
+	b.WriteString("#")
+	for _, t := range tokens {
+		t = strings.ReplaceAll(t, "~", "~0")
+		t = strings.ReplaceAll(t, "/", "~1")
+		b.WriteString("/")
+		b.WriteString(t)
+	}
+	return b.String()
+}
+
+func (g *Generator) setOrigin(ptr string, pos token.Pos) {
+	g.origins[ptr] = pos
+}
+
+// origin returns the position of the declaration that produced ptr,
+// falling back to the nearest parent that has one.
+func (g *Generator) origin(ptr string) token.Pos {
+	for {
+		pos, ok := g.origins[ptr]
+		if ok {
+			return pos
+		}
+		i := strings.LastIndex(ptr, "/")
+		if i < 0 {
+			return token.NoPos
+		}
+		ptr = ptr[:i]
+	}
+}
+
+// validate checks the generated spec and reports problems against the Go declarations.
+func (g *Generator) validate(ctx context.Context) {
+	if !g.checkRefs() {
+		return
+	}
+
+	// Validate each piece alone so an error is reported once, at its own declaration.
+	ok := true
+	for _, name := range sortedSchemaNames(g.spec.Components.Schemas) {
+		s := &openapi3.SchemaRef{}
+		Convert(g.spec.Components.Schemas[name], s)
+		stubSchemaRefs(s)
+		err := s.Validate(ctx)
+		if err != nil {
+			g.errorf(g.origin(pointer("components", "schemas", name)), "schema %v: %v", name, err)
+			ok = false
+		}
+	}
+	for _, path := range sortedPaths(g.spec.Paths) {
+		item := g.spec.Paths[path]
+		for _, method := range sortedMethods(item) {
+			ope := &openapi3.Operation{}
+			Convert(item.GetOperation(method), ope)
+			eachOperationSchema(ope, stubSchemaRefs)
+			err := ope.Validate(ctx)
+			if err != nil {
+				g.errorf(g.origin(pointer("paths", path, strings.ToLower(method))), "%v %v: %v", method, path, err)
+				ok = false
+			}
+		}
+	}
+	// the loader does not terminate on some broken specs, e.g. a cycle of $refs.
+	if !ok || HasError(g.diags) {
+		return
+	}
+
+	data, err := json.Marshal(g.spec)
+	if err != nil {
+		g.errorf(token.NoPos, "json.Marshal: %v", err)
+		return
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		g.errorf(token.NoPos, "openapi3.Loader: %v", err)
+		return
+	}
+	err = doc.Validate(ctx)
+	if err != nil {
+		g.errorf(token.NoPos, "%v", err)
+	}
+}
+
+// checkRefs reports every $ref to a schema that is not defined in components.
+func (g *Generator) checkRefs() bool {
+	ok := true
+	check := func(ptr string, ref *openapi3.SchemaRef) {
+		walkSchemaRefs(ref, func(r *openapi3.SchemaRef) {
+			if r.Ref == "" {
+				return
+			}
+			name := strings.TrimPrefix(r.Ref, schemaRefPrefix)
+			if name != r.Ref && g.spec.Components.Schemas[name] != nil {
+				return
+			}
+			g.errorf(g.origin(ptr), "unresolved ref %v", r.Ref)
+			ok = false
+		})
+	}
+
+	for _, name := range sortedSchemaNames(g.spec.Components.Schemas) {
+		check(pointer("components", "schemas", name), g.spec.Components.Schemas[name])
+	}
+	for _, path := range sortedPaths(g.spec.Paths) {
+		item := g.spec.Paths[path]
+		for _, method := range sortedMethods(item) {
+			ptr := pointer("paths", path, strings.ToLower(method))
+			eachOperationSchema(item.GetOperation(method), func(ref *openapi3.SchemaRef) {
+				check(ptr, ref)
+			})
+		}
+	}
+	return ok
+}
+
+// eachOperationSchema calls fn for the schema of every parameter, request body and response of ope.
+func eachOperationSchema(ope *openapi3.Operation, fn func(*openapi3.SchemaRef)) {
+	for _, p := range ope.Parameters {
+		if p.Value != nil && p.Value.Schema != nil {
+			fn(p.Value.Schema)
+		}
+	}
+	if ope.RequestBody != nil && ope.RequestBody.Value != nil {
+		for _, mt := range ope.RequestBody.Value.Content {
+			if mt.Schema != nil {
+				fn(mt.Schema)
+			}
+		}
+	}
+	for _, code := range sortedResponseCodes(ope.Responses) {
+		res := ope.Responses[code]
+		if res.Value == nil {
+			continue
+		}
+		for _, mt := range res.Value.Content {
+			if mt.Schema != nil {
+				fn(mt.Schema)
+			}
+		}
+	}
+}
+
+// stubSchemaRefs replaces the targets of $refs with empty schemas,
+// so that validating ref does not descend into other components.
+func stubSchemaRefs(ref *openapi3.SchemaRef) {
+	walkSchemaRefs(ref, func(r *openapi3.SchemaRef) {
+		if r.Ref != "" {
+			r.Value = &openapi3.Schema{}
+		}
+	})
+}
+
+// walkSchemaRefs calls fn for ref and every schema nested inline in it.
+// $refs are not followed.
+func walkSchemaRefs(ref *openapi3.SchemaRef, fn func(*openapi3.SchemaRef)) {
+	if ref == nil {
+		return
+	}
+	fn(ref)
+	if ref.Ref != "" || ref.Value == nil {
+		return
+	}
+	s := ref.Value
+	for _, r := range s.OneOf {
+		walkSchemaRefs(r, fn)
+	}
+	for _, r := range s.AnyOf {
+		walkSchemaRefs(r, fn)
+	}
+	for _, r := range s.AllOf {
+		walkSchemaRefs(r, fn)
+	}
+	walkSchemaRefs(s.Not, fn)
+	walkSchemaRefs(s.Items, fn)
+	walkSchemaRefs(s.AdditionalProperties, fn)
+	for _, name := range sortedSchemaNames(s.Properties) {
+		walkSchemaRefs(s.Properties[name], fn)
+	}
+}
+
+func sortedSchemaNames(schemas openapi3.Schemas) []string {
+	names := make([]string, 0, len(schemas))
+	for k := range schemas {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedPaths(paths openapi3.Paths) []string {
+	keys := make([]string, 0, len(paths))
+	for k := range paths {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedMethods(item *openapi3.PathItem) []string {
+	methods := []string{}
+	for method := range item.Operations() {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+func sortedResponseCodes(responses openapi3.Responses) []string {
+	codes := make([]string, 0, len(responses))
+	for k := range responses {
+		codes = append(codes, k)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// checkCycles reports schemas that are defined by themselves through $ref or allOf.
+// Recursion through properties or items is fine.
+func (g *Generator) checkCycles() {
+	schemas := g.spec.Components.Schemas
+	deps := func(name string) []string {
+		r := schemas[name]
+		if r == nil {
+			return nil
+		}
+		refs := openapi3.SchemaRefs{r}
+		if r.Value != nil {
+			refs = r.Value.AllOf
+		}
+		names := []string{}
+		for _, r := range refs {
+			if strings.HasPrefix(r.Ref, schemaRefPrefix) {
+				names = append(names, strings.TrimPrefix(r.Ref, schemaRefPrefix))
+			}
+		}
+		return names
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var visit func(name string, path []string)
+	visit = func(name string, path []string) {
+		switch state[name] {
+		case visiting:
+			i := 0
+			for path[i] != name {
+				i++
+			}
+			cycle := append(path[i:], name)
+			g.errorf(g.origin(pointer("components", "schemas", name)), "cycle in schema definition: %v", strings.Join(cycle, " -> "))
+			return
+		case done:
+			return
+		}
+		state[name] = visiting
+		for _, d := range deps(name) {
+			visit(d, append(path, name))
+		}
+		state[name] = done
+	}
+	for _, name := range sortedSchemaNames(schemas) {
+		visit(name, nil)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ValidateTag is a go-playground/validator tag translated into the shorthands of ExpandTagForScheme.
+type ValidateTag struct {
+	Required  bool
+	OmitEmpty bool
+	KV        KeyValue
+	// OneOf is typed by the schema, see ExpandValidateTagForScheme.
+	OneOf []string
+	// Items are the rules after "dive".
+	Items *ValidateTag
+}
+
+var validateFormats = map[string]string{
+	"email":    "email",
+	"url":      "uri",
+	"uri":      "uri",
+	"uuid":     "uuid",
+	"uuid4":    "uuid",
+	"hostname": "hostname",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+}
+
+var validateShorthands = map[string]string{
+	"min": "min",
+	"max": "max",
+	"len": "len",
+	"gt":  "gt",
+	"gte": "gte",
+	"lt":  "lt",
+	"lte": "lte",
+}
+
+// GetValidateTag returns the "validate" tag, or the "binding" tag of gin.
+func GetValidateTag(tag string) string {
+	st := reflect.StructTag(tag)
+	v, ok := st.Lookup("validate")
+	if ok {
+		return v
+	}
+	return st.Get("binding")
+}
+
+func ParseValidateTag(tag string) *ValidateTag {
+	vt := &ValidateTag{KV: KeyValue{}}
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		key := rule
+		value := ""
+		j := strings.Index(rule, "=")
+		if j >= 0 {
+			key = rule[:j]
+			value = rule[j+1:]
+		}
+
+		switch key {
+		case "required":
+			vt.Required = true
+		case "omitempty":
+			vt.OmitEmpty = true
+		case "dive":
+			vt.Items = ParseValidateTag(strings.Join(rules[i+1:], ","))
+			return vt
+		case "oneof":
+			vt.OneOf = splitOneOf(value)
+		case "datetime":
+			format := datetimeFormat(value)
+			if format != "" {
+				vt.KV["format"] = format
+			}
+		case "unique":
+			vt.KV["unique"] = true
+		default:
+			shorthand, ok := validateShorthands[key]
+			if ok {
+				n, ok := parseNumber(value)
+				if ok {
+					vt.KV[shorthand] = n
+				}
+				continue
+			}
+			format, ok := validateFormats[key]
+			if ok {
+				vt.KV["format"] = format
+			}
+		}
+	}
+	return vt
+}
+
+var oneOfPattern = regexp.MustCompile(`'[^']*'|\S+`)
+
+// splitOneOf splits the values of oneof like the validator, a value with spaces is in single quotes: 'a b' c.
+func splitOneOf(value string) []string {
+	values := oneOfPattern.FindAllString(value, -1)
+	for i, v := range values {
+		if len(v) >= 2 && strings.HasPrefix(v, "'") && strings.HasSuffix(v, "'") {
+			values[i] = v[1 : len(v)-1]
+		}
+	}
+	return values
+}
+
+// datetimeFormat returns the format of the Go time layout of datetime:
+// date for 2006-01-02, date-time for RFC 3339 layouts and none for the others.
+func datetimeFormat(layout string) string {
+	switch {
+	case layout == "" || strings.HasPrefix(layout, "2006-01-02T15:04:05"):
+		return "date-time"
+	case layout == "2006-01-02":
+		return "date"
+	}
+	return ""
+}
+
+func parseNumber(s string) (float64, bool) {
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil
+}
+
+// ExpandValidateTagForScheme sets the constraints of vt to scheme, and to its items after "dive".
+func ExpandValidateTagForScheme(scheme *openapi3.Schema, vt *ValidateTag) error {
+	if scheme == nil || vt == nil {
+		return nil
+	}
+	if len(vt.OneOf) > 0 {
+		scheme.Enum = typedEnum(scheme.Type, vt.OneOf)
+	}
+	if len(vt.KV) > 0 {
+		err := ExpandTagForScheme(scheme, vt.KV)
+		if err != nil {
+			return err
+		}
+	}
+	if vt.Items != nil {
+		items := scheme.Items
+		if items == nil {
+			items = scheme.AdditionalProperties
+		}
+		if items == nil {
+			return errors.New("dive is not applicable to type " + strconv.Quote(scheme.Type))
+		}
+		return ExpandValidateTagForScheme(items.Value, vt.Items)
+	}
+	return nil
+}
+
+func typedEnum(ty string, values []string) []interface{} {
+	enum := []interface{}{}
+	for _, v := range values {
+		switch ty {
+		case "integer":
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err == nil {
+				enum = append(enum, n)
+				continue
+			}
+		case "number":
+			n, err := strconv.ParseFloat(v, 64)
+			if err == nil {
+				enum = append(enum, n)
+				continue
+			}
+		case "boolean":
+			b, err := strconv.ParseBool(v)
+			if err == nil {
+				enum = append(enum, b)
+				continue
+			}
+		}
+		enum = append(enum, v)
+	}
+	return enum
+}
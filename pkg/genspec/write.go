@@ -0,0 +1,96 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genspec
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+)
+
+// FormatOf returns the format of a file by its extension, FormatYAML unless ".json".
+func FormatOf(file string) Format {
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// WriteTo writes t to w in format, the root keys in the usual order.
+func WriteTo(w io.Writer, t *openapi3.T, format Format) error {
+	var data []byte
+	var err error
+	switch format {
+	case FormatYAML, "":
+		data, err = marshalYAML(t)
+	case FormatJSON:
+		data, err = json.MarshalIndent(t, "", "  ")
+		data = append(data, '\n')
+	default:
+		return errors.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return errors.Wrap(err, "w.Write")
+	}
+	return nil
+}
+
+func marshalYAML(t *openapi3.T) ([]byte, error) {
+	kv := KeyValue{}
+	err := Convert(t, &kv)
+	if err != nil {
+		return nil, errors.Wrap(err, "Convert Scheme to KeyValue")
+	}
+	// KeyValue -> MapSlice
+	ms := yaml.MapSlice{}
+	keys := []string{"openapi", "info", "servers", "tags", "externalDocs", "security", "paths", "components"}
+	for _, k := range keys {
+		v := kv[k]
+		if v != nil {
+			ms = append(ms, yaml.MapItem{
+				Key:   k,
+				Value: v,
+			})
+			delete(kv, k)
+		}
+	}
+	for _, k := range sortedTagKeys(kv) {
+		ms = append(ms, yaml.MapItem{
+			Key:   k,
+			Value: kv[k],
+		})
+	}
+	bytes, err := yaml.Marshal(&ms)
+	if err != nil {
+		return nil, errors.Wrap(err, "yaml.Marshal")
+	}
+	return bytes, nil
+}
@@ -0,0 +1,359 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package project reads the project file, .openapi-spec.yaml, declaring the APIs of a repository:
+//
+//	defaults:
+//	  format: yaml
+//	  openapi: 3.0.3
+//	  typeMappings:
+//	    decimal.Decimal: {type: string, format: decimal}
+//	apis:
+//	- name: pet
+//	  input: api/pet/spec.go
+//	  output: api/pet/openapi.yaml
+//	  package: api
+//	  flatten: true
+//	  overlays: [api/pet/gateway.yaml]
+//	  impl: {template: templates/serverimpl.go.tmpl, output: api/pet/server.go}
+//	  client: {output: client/pet/client.go, package: pet}
+//	  lint: {operation-description: off}
+//
+// The values of an API win over the defaults, and the paths are relative to the directory of the file.
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genspec"
+	"gopkg.in/yaml.v2"
+)
+
+// FileName is the name of the project file, found by walking up from the working directory.
+const FileName = ".openapi-spec.yaml"
+
+type Project struct {
+	// File is the path of the project file.
+	File     string `json:"-"`
+	Defaults API    `json:"defaults"`
+	APIs     []*API `json:"apis"`
+}
+
+// API is an API of the project. The options not set are nil, to be overridden by flags.
+type API struct {
+	Name          string                      `json:"name"`
+	Input         string                      `json:"input"`
+	Output        string                      `json:"output"`
+	Package       string                      `json:"package"`
+	Format        genspec.Format              `json:"format"`
+	OpenAPI       string                      `json:"openapi"`
+	Flatten       *bool                       `json:"flatten"`
+	SplitRW       *bool                       `json:"splitRW"`
+	Strict        *bool                       `json:"strict"`
+	FailOnInvalid *bool                       `json:"failOnInvalid"`
+	Overlays      []string                    `json:"overlays"`
+	TypeMappings  map[string]*openapi3.Schema `json:"typeMappings"`
+	Impl          *Target                     `json:"impl"`
+	Client        *Target                     `json:"client"`
+	// Lint is the severity of the lint rules by name.
+	Lint map[string]Severity `json:"lint"`
+}
+
+// Target is a Go source generated from the spec.
+type Target struct {
+	Template string `json:"template"`
+	Output   string `json:"output"`
+	Package  string `json:"package"`
+}
+
+// Severity of a lint rule, "off", "warning" or "error".
+type Severity string
+
+// UnmarshalJSON accepts false for "off", YAML reads a bare off as false.
+func (s *Severity) UnmarshalJSON(data []byte) error {
+	if string(data) == "false" {
+		*s = "off"
+		return nil
+	}
+	return json.Unmarshal(data, (*string)(s))
+}
+
+// Find returns the project file in dir or its parents, or "" if there is none.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		file := filepath.Join(dir, FileName)
+		_, err := os.Stat(file)
+		if err == nil {
+			return file, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads the project file.
+func Load(file string) (*Project, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(file, data)
+}
+
+// Parse parses the project file, the paths are resolved from the directory of file.
+// An unknown key, e.g. a misspelled one, is an error.
+func Parse(file string, data []byte) (*Project, error) {
+	var obj interface{}
+	err := yaml.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, errors.Wrap(err, file)
+	}
+	b, err := json.Marshal(genspec.JSONValue(obj))
+	if err != nil {
+		return nil, errors.Wrap(err, file)
+	}
+	p := &Project{File: file}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(p)
+	if err == nil {
+		err = p.init()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, file)
+	}
+	return p, nil
+}
+
+var (
+	openAPIVersion = regexp.MustCompile(`^3\.0\.\d+$`)
+	severities     = map[Severity]bool{"off": true, "warning": true, "error": true}
+)
+
+// init merges the defaults into the APIs, checks them and resolves the paths.
+func (p *Project) init() error {
+	dir, err := filepath.Abs(filepath.Dir(p.File))
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for i, api := range p.APIs {
+		if api == nil {
+			return errors.Errorf("apis[%v] is empty", i)
+		}
+		where := fmt.Sprintf("apis[%v]", i)
+		if api.Name != "" {
+			where = fmt.Sprintf("api %v", api.Name)
+		}
+		if len(p.APIs) > 1 && api.Name == "" {
+			return errors.Errorf("%v: name is required for more than one API", where)
+		}
+		if names[api.Name] {
+			return errors.Errorf("duplicate api %v", api.Name)
+		}
+		names[api.Name] = true
+		api.merge(&p.Defaults)
+		if api.Input == "" {
+			return errors.Errorf("%v: input is required", where)
+		}
+		err := api.check()
+		if err != nil {
+			return errors.Wrap(err, where)
+		}
+		api.resolve(dir)
+	}
+	err = p.Defaults.check()
+	if err != nil {
+		return errors.Wrap(err, "defaults")
+	}
+	p.Defaults.resolve(dir)
+	return nil
+}
+
+func (a *API) check() error {
+	switch a.Format {
+	case "", genspec.FormatYAML, genspec.FormatJSON:
+	default:
+		return errors.Errorf("unknown format %q", a.Format)
+	}
+	if a.OpenAPI != "" && !openAPIVersion.MatchString(a.OpenAPI) {
+		return errors.Errorf("unsupported openapi %q, only 3.0.x is supported", a.OpenAPI)
+	}
+	for rule, severity := range a.Lint {
+		if !severities[severity] {
+			return errors.Errorf("lint %v: unknown severity %q", rule, severity)
+		}
+	}
+	if a.Impl != nil && a.Impl.Template == "" {
+		return errors.New("impl: template is required")
+	}
+	return nil
+}
+
+// merge sets the options of a not set from d.
+func (a *API) merge(d *API) {
+	setString := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	setBool := func(dst **bool, src *bool) {
+		if *dst == nil {
+			*dst = src
+		}
+	}
+	setString(&a.Package, d.Package)
+	setString((*string)(&a.Format), string(d.Format))
+	setString(&a.OpenAPI, d.OpenAPI)
+	setBool(&a.Flatten, d.Flatten)
+	setBool(&a.SplitRW, d.SplitRW)
+	setBool(&a.Strict, d.Strict)
+	setBool(&a.FailOnInvalid, d.FailOnInvalid)
+	if a.Overlays == nil {
+		a.Overlays = d.Overlays
+	}
+	a.TypeMappings = mergeMap(a.TypeMappings, d.TypeMappings).(map[string]*openapi3.Schema)
+	a.Lint = mergeMap(a.Lint, d.Lint).(map[string]Severity)
+	if a.Impl == nil && d.Impl != nil {
+		impl := *d.Impl
+		a.Impl = &impl
+	}
+	if a.Client == nil && d.Client != nil {
+		client := *d.Client
+		a.Client = &client
+	}
+}
+
+// mergeMap returns a copy of d with the entries of a.
+func mergeMap(a, d interface{}) interface{} {
+	switch a := a.(type) {
+	case map[string]*openapi3.Schema:
+		m := map[string]*openapi3.Schema{}
+		for k, v := range d.(map[string]*openapi3.Schema) {
+			m[k] = v
+		}
+		for k, v := range a {
+			m[k] = v
+		}
+		return m
+	case map[string]Severity:
+		m := map[string]Severity{}
+		for k, v := range d.(map[string]Severity) {
+			m[k] = v
+		}
+		for k, v := range a {
+			m[k] = v
+		}
+		return m
+	}
+	return a
+}
+
+func (a *API) resolve(dir string) {
+	join := func(path *string) {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+	join(&a.Input)
+	join(&a.Output)
+	overlays := make([]string, len(a.Overlays))
+	for i := range a.Overlays {
+		overlays[i] = a.Overlays[i]
+		join(&overlays[i])
+	}
+	a.Overlays = overlays
+	for _, t := range []*Target{a.Impl, a.Client} {
+		if t != nil {
+			join(&t.Template)
+			join(&t.Output)
+		}
+	}
+}
+
+// Select returns the API of the name, or all the APIs if name is "".
+func (p *Project) Select(name string) ([]*API, error) {
+	if name == "" {
+		return p.APIs, nil
+	}
+	for _, api := range p.APIs {
+		if api.Name == name {
+			return []*API{api}, nil
+		}
+	}
+	return nil, errors.Errorf("%v: no api %v", p.File, name)
+}
+
+// ForInput returns the API of the input file, or the defaults with the input.
+func (p *Project) ForInput(input string) *API {
+	abs, err := filepath.Abs(input)
+	if err == nil {
+		for _, api := range p.APIs {
+			if api.Input == abs {
+				return api
+			}
+		}
+	}
+	api := p.Defaults
+	api.Input = input
+	return &api
+}
+
+// Config returns the genspec.Config of the API.
+func (a *API) Config() *genspec.Config {
+	config := &genspec.Config{
+		PackageName: a.Package,
+		InputFile:   a.Input,
+		OutputFile:  a.Output,
+		OpenAPI:     a.OpenAPI,
+		Format:      a.Format,
+		Overlays:    append([]string{}, a.Overlays...),
+	}
+	isSet := func(b *bool) bool {
+		return b != nil && *b
+	}
+	config.FlattenEmbedded = isSet(a.Flatten)
+	config.SplitReadWrite = isSet(a.SplitRW)
+	config.StrictTags = isSet(a.Strict)
+	config.FailOnInvalid = isSet(a.FailOnInvalid)
+	if len(a.TypeMappings) > 0 {
+		config.TypeMappers = []genspec.TypeMapper{genspec.SchemaMap(a.TypeMappings)}
+	}
+	return config
+}
+
+// TargetPackage returns the package of t, the package of the API by default.
+func (a *API) TargetPackage(t *Target) string {
+	if t.Package != "" {
+		return t.Package
+	}
+	return a.Package
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genspec"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/project"
+)
+
+const projectSrc = `
+defaults:
+  package: api
+  format: json
+  openapi: 3.0.3
+  strict: true
+  typeMappings:
+    decimal.Decimal: {type: string, format: decimal}
+  lint: {operation-description: warning}
+  client: {package: client}
+apis:
+- name: pet
+  input: pet/spec.go
+  output: pet/openapi.json
+  strict: false
+  overlays: [pet/gateway.yaml]
+  impl: {template: templates/server.go.tmpl, output: pet/server.go}
+  lint: {operation-description: off}
+- name: store
+  input: store/spec.go
+  format: yaml
+  typeMappings:
+    uuid.UUID: {type: string, format: uuid}
+`
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "a", "b")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+
+	file, err := project.Find(sub)
+	require.NoError(t, err)
+	require.Equal(t, "", file)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, project.FileName), []byte(projectSrc), 0644))
+	file, err = project.Find(sub)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, project.FileName), file)
+
+	p, err := project.Load(file)
+	require.NoError(t, err)
+	require.Len(t, p.APIs, 2)
+}
+
+func TestParse(t *testing.T) {
+	p, err := project.Parse("/repo/"+project.FileName, []byte(projectSrc))
+	require.NoError(t, err)
+
+	pet := p.APIs[0]
+	require.Equal(t, "/repo/pet/spec.go", pet.Input)
+	require.Equal(t, []string{"/repo/pet/gateway.yaml"}, pet.Overlays)
+	require.Equal(t, "/repo/templates/server.go.tmpl", pet.Impl.Template)
+	require.Equal(t, "client", pet.TargetPackage(pet.Client))
+	require.Equal(t, "api", pet.TargetPackage(pet.Impl))
+	require.Equal(t, map[string]project.Severity{"operation-description": "off"}, pet.Lint)
+
+	config := pet.Config()
+	require.Equal(t, "api", config.PackageName)
+	require.Equal(t, "/repo/pet/openapi.json", config.OutputFile)
+	require.Equal(t, genspec.FormatJSON, config.Format)
+	require.Equal(t, "3.0.3", config.OpenAPI)
+	require.False(t, config.StrictTags)
+	require.Len(t, config.TypeMappers, 1)
+	require.Equal(t, "decimal", config.TypeMappers[0].MapType("decimal.Decimal").Format)
+
+	store := p.APIs[1]
+	config = store.Config()
+	require.Equal(t, genspec.FormatYAML, config.Format)
+	require.True(t, config.StrictTags)
+	require.Nil(t, store.Impl)
+	require.Equal(t, "decimal", config.TypeMappers[0].MapType("decimal.Decimal").Format)
+	require.Equal(t, "uuid", config.TypeMappers[0].MapType("uuid.UUID").Format)
+	require.Equal(t, map[string]project.Severity{"operation-description": "warning"}, store.Lint)
+
+	apis, err := p.Select("store")
+	require.NoError(t, err)
+	require.Equal(t, []*project.API{store}, apis)
+	_, err = p.Select("none")
+	require.EqualError(t, err, "/repo/.openapi-spec.yaml: no api none")
+
+	require.Equal(t, pet, p.ForInput("/repo/pet/spec.go"))
+	other := p.ForInput("other.go")
+	require.Equal(t, "other.go", other.Input)
+	require.Equal(t, "3.0.3", other.OpenAPI)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"apis: [{input: a.go}, {input: b.go}]", "apis[0]: name is required for more than one API"},
+		{"apis: [{name: a, input: a.go}, {name: a, input: b.go}]", "duplicate api a"},
+		{"apis: [{name: a}]", "api a: input is required"},
+		{"apis: [{input: a.go, format: xml}]", `apis[0]: unknown format "xml"`},
+		{"apis: [{input: a.go, openapi: 3.1.0}]", `apis[0]: unsupported openapi "3.1.0", only 3.0.x is supported`},
+		{"defaults: {lint: {x: never}}\napis: [{input: a.go}]", `apis[0]: lint x: unknown severity "never"`},
+		{"apis: [{input: a.go, impl: {output: x.go}}]", "apis[0]: impl: template is required"},
+		{"apis: {}", "json: cannot unmarshal object into Go struct field Project.apis of type []*project.API"},
+		{"apis: [{input: a.go, overlay: [x.yaml]}]", `json: unknown field "overlay"`},
+		{"default: {format: json}\napis: [{input: a.go}]", `json: unknown field "default"`},
+	}
+	for _, tt := range tests {
+		_, err := project.Parse(project.FileName, []byte(tt.src))
+		require.EqualError(t, err, project.FileName+": "+tt.err, tt.src)
+	}
+}
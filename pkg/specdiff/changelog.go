@@ -0,0 +1,207 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package specdiff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// changelog is the changes grouped by the tags, "" for the changes of the root.
+type changelog struct {
+	old, new *openapi3.T
+	groups   map[string]*changeGroup
+	tags     []string
+}
+
+type changeGroup struct {
+	entries []*changeEntry
+	byKey   map[string]*changeEntry
+}
+
+// changeEntry is a line of the changelog, with the changes of an operation or a schema as details.
+type changeEntry struct {
+	text     string
+	breaking bool
+	details  []Change
+}
+
+const (
+	otherTag   = "\x00other"
+	schemasTag = "\x00schemas"
+)
+
+// WriteChangelog writes the Markdown changelog of the report, the changes grouped by the first tag of
+// the operations and by the operations. old and new are the specs compared, for the tags and the summaries.
+func (r *Report) WriteChangelog(w io.Writer, old, new *openapi3.T, title string) error {
+	l := &changelog{old: old, new: new, groups: map[string]*changeGroup{}}
+	for _, c := range r.Changes {
+		l.add(c)
+	}
+
+	b := &strings.Builder{}
+	if title != "" {
+		fmt.Fprintf(b, "# %v\n\n", title)
+	}
+	if len(r.Changes) == 0 {
+		b.WriteString("No changes of the API.\n\n")
+	}
+	for _, tag := range l.order() {
+		switch tag {
+		case "":
+		case otherTag:
+			b.WriteString("## Other\n\n")
+		case schemasTag:
+			b.WriteString("## Schemas\n\n")
+		default:
+			fmt.Fprintf(b, "## %v\n\n", tag)
+		}
+		for _, e := range l.groups[tag].entries {
+			fmt.Fprintf(b, "- %v%v\n", e.text, breakingMark(e.breaking))
+			for _, c := range e.details {
+				fmt.Fprintf(b, "  - %v%v\n", c.Message, breakingMark(c.Severity == Breaking))
+			}
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, strings.TrimSuffix(b.String(), "\n"))
+	return err
+}
+
+func breakingMark(breaking bool) string {
+	if breaking {
+		return " **(breaking)**"
+	}
+	return ""
+}
+
+func (l *changelog) add(c Change) {
+	if c.Operation == "" {
+		if c.Schema == "" {
+			l.entry("", "", strings.ToUpper(c.Message[:1])+c.Message[1:]).breaking = c.Severity == Breaking
+			return
+		}
+		if c.Kind == "deprecated" {
+			// once for the requests and the responses.
+			if c.Property == "" {
+				l.entry(schemasTag, "deprecated "+c.Schema, "Deprecated schema "+c.Schema)
+			} else {
+				l.entry(schemasTag, "deprecated "+c.Schema+"."+c.Property, "Deprecated field "+c.Schema+"."+c.Property)
+			}
+			return
+		}
+		// the details are under the schema, "request: ..." without "request schema Name".
+		c.Message = strings.TrimSpace(strings.TrimPrefix(c.Message, c.Direction+" schema "+c.Schema))
+		c.Message = c.Direction + ": " + strings.TrimSpace(strings.TrimPrefix(c.Message, ":"))
+		e := l.entry(schemasTag, "schema "+c.Schema, fmt.Sprintf("Changed schema `%v`", c.Schema))
+		e.details = append(e.details, c)
+		return
+	}
+
+	tag, summary := l.operation(c.Operation)
+	op := "`" + c.Operation + "`"
+	if summary != "" {
+		op += ": " + summary
+	}
+	switch c.Kind {
+	case "operation-added":
+		l.entry(tag, "", "Added "+op)
+	case "operation-removed":
+		l.entry(tag, "", "Removed "+op).breaking = true
+	case "operation-deprecated":
+		l.entry(tag, "", "Deprecated "+op)
+	default:
+		e := l.entry(tag, c.Operation, "Changed "+op)
+		e.details = append(e.details, c)
+	}
+}
+
+// entry returns the entry of the key in the group of tag, a new entry if key is "" or not found.
+func (l *changelog) entry(tag, key, text string) *changeEntry {
+	g, ok := l.groups[tag]
+	if !ok {
+		g = &changeGroup{byKey: map[string]*changeEntry{}}
+		l.groups[tag] = g
+		l.tags = append(l.tags, tag)
+	}
+	if e, ok := g.byKey[key]; ok && key != "" {
+		return e
+	}
+	e := &changeEntry{text: text}
+	g.entries = append(g.entries, e)
+	g.byKey[key] = e
+	return e
+}
+
+// operation returns the first tag and the summary of the operation "METHOD /path",
+// of new or else of old. The summary is the first line of the description by default.
+func (l *changelog) operation(name string) (string, string) {
+	i := strings.Index(name, " ")
+	method, path := name[:i], name[i+1:]
+	var ope *openapi3.Operation
+	for _, t := range []*openapi3.T{l.new, l.old} {
+		if item := t.Paths[path]; item != nil {
+			if ope = item.GetOperation(method); ope != nil {
+				break
+			}
+		}
+	}
+	if ope == nil {
+		return otherTag, ""
+	}
+	tag := otherTag
+	if len(ope.Tags) > 0 {
+		tag = ope.Tags[0]
+	}
+	summary := ope.Summary
+	if summary == "" {
+		summary = strings.TrimSpace(strings.SplitN(ope.Description, "\n", 2)[0])
+	}
+	return tag, summary
+}
+
+// order returns the tags: the root, the tags declared in new and old, the others sorted, and the schemas.
+func (l *changelog) order() []string {
+	rank := map[string]int{"": 0}
+	for _, t := range []*openapi3.T{l.new, l.old} {
+		for _, tag := range t.Tags {
+			if _, ok := rank[tag.Name]; !ok {
+				rank[tag.Name] = len(rank)
+			}
+		}
+	}
+	tags := append([]string{}, l.tags...)
+	last := map[string]int{otherTag: 1, schemasTag: 2}
+	sort.SliceStable(tags, func(i, j int) bool {
+		a, b := tags[i], tags[j]
+		if last[a] != last[b] {
+			return last[a] < last[b]
+		}
+		ra, oka := rank[a]
+		rb, okb := rank[b]
+		switch {
+		case oka && okb:
+			return ra < rb
+		case oka != okb:
+			return oka
+		}
+		return a < b
+	})
+	return tags
+}
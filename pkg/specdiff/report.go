@@ -0,0 +1,106 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package specdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatJSON     Format = "json"
+	FormatMarkdown Format = "markdown"
+)
+
+// Write writes the report in format, the markdown is for the comments of pull requests.
+func (r *Report) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatText, "":
+		return r.writeText(w)
+	case FormatJSON:
+		return r.writeJSON(w)
+	case FormatMarkdown:
+		return r.writeMarkdown(w)
+	}
+	return errors.Errorf("unknown format %q", format)
+}
+
+func (r *Report) writeText(w io.Writer) error {
+	for _, c := range r.Changes {
+		_, err := fmt.Fprintln(w, c.String())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Report) writeJSON(w io.Writer) error {
+	changes := r.Changes
+	if changes == nil {
+		changes = []Change{}
+	}
+	data, err := json.MarshalIndent(struct {
+		Breaking    int      `json:"breaking"`
+		NonBreaking int      `json:"nonBreaking"`
+		Changes     []Change `json:"changes"`
+	}{r.Count(Breaking), r.Count(NonBreaking), changes}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func (r *Report) writeMarkdown(w io.Writer) error {
+	b := &strings.Builder{}
+	if len(r.Changes) == 0 {
+		b.WriteString("No changes of the API.\n")
+	}
+	for _, section := range []struct {
+		severity Severity
+		title    string
+	}{
+		{Breaking, "Breaking changes"},
+		{NonBreaking, "Non-breaking changes"},
+	} {
+		n := r.Count(section.severity)
+		if n == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "### %v (%v)\n\n", section.title, n)
+		b.WriteString("| Operation | Change |\n| --- | --- |\n")
+		for _, c := range r.Changes {
+			if c.Severity != section.severity {
+				continue
+			}
+			op := c.Operation
+			if op != "" {
+				op = "`" + op + "`"
+			}
+			fmt.Fprintf(b, "| %v | %v |\n", op, strings.ReplaceAll(c.Message, "|", "\\|"))
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, strings.TrimSuffix(b.String(), "\n"))
+	return err
+}
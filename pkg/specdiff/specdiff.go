@@ -0,0 +1,723 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package specdiff compares two versions of a spec and classifies the changes for the clients.
+//
+// A change is breaking when a client working with the old spec may fail with the new one:
+// a removed operation, a new required parameter or property of the request, a narrowed enum
+// or range of the request, a changed type, a removed property, a new enum value or a widened range of the response.
+// The members of oneOf and anyOf are compared as the enum values.
+package specdiff
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type Severity string
+
+const (
+	Breaking    Severity = "breaking"
+	NonBreaking Severity = "non-breaking"
+)
+
+// Change is a difference of the specs. Operation is "METHOD /path", or "" for the root of the spec.
+type Change struct {
+	Severity  Severity `json:"severity"`
+	Kind      string   `json:"kind"`
+	Operation string   `json:"operation,omitempty"`
+	// Schema is the component schema of a change reported once for the schema, not for each operation.
+	Schema string `json:"schema,omitempty"`
+	// Property is the path of the property in the schema, "owner.name".
+	Property string `json:"property,omitempty"`
+	// Direction is "request" or "response" for a change of a schema, a component is compared for each.
+	Direction string `json:"direction,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (c Change) String() string {
+	if c.Operation == "" {
+		return fmt.Sprintf("%v: %v", c.Severity, c.Message)
+	}
+	return fmt.Sprintf("%v: %v: %v", c.Severity, c.Operation, c.Message)
+}
+
+// Report is the changes from the old spec to the new one, the operations in the order of the paths.
+type Report struct {
+	Changes []Change `json:"changes"`
+}
+
+// Count returns the number of the changes of severity.
+func (r *Report) Count(severity Severity) int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+type differ struct {
+	old, new *openapi3.T
+	op       string
+	// the component schema, the path of the property and the direction compared
+	schemaName, property, direction string
+	changes                         []Change
+	// the pairs of the component schemas compared, by direction
+	visited map[string]bool
+}
+
+// Compare returns the changes from old to new.
+func Compare(old, new *openapi3.T) *Report {
+	d := &differ{old: old, new: new, visited: map[string]bool{}}
+	d.info()
+	d.paths()
+	return &Report{Changes: d.changes}
+}
+
+func (d *differ) add(severity Severity, kind, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{
+		Severity:  severity,
+		Kind:      kind,
+		Operation: d.op,
+		Schema:    d.schemaName,
+		Property:  d.property,
+		Direction: d.direction,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *differ) info() {
+	if d.old.Info != nil && d.new.Info != nil && d.old.Info.Version != d.new.Info.Version {
+		d.add(NonBreaking, "version-changed", "version changed from %v to %v", d.old.Info.Version, d.new.Info.Version)
+	}
+}
+
+var pathParam = regexp.MustCompile(`\{[^}]*\}`)
+
+// pathKey returns the path without the names of the path parameters, {id} and {petId} are the same.
+func pathKey(path string) string {
+	return pathParam.ReplaceAllString(path, "{}")
+}
+
+var methods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"}
+
+func (d *differ) paths() {
+	oldPaths := map[string]string{}
+	for path := range d.old.Paths {
+		oldPaths[pathKey(path)] = path
+	}
+	newPaths := map[string]string{}
+	for path := range d.new.Paths {
+		newPaths[pathKey(path)] = path
+	}
+	keys := []string{}
+	for k := range oldPaths {
+		keys = append(keys, k)
+	}
+	for k := range newPaths {
+		if _, ok := oldPaths[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var oldItem, newItem *openapi3.PathItem
+		path := newPaths[k]
+		if p, ok := oldPaths[k]; ok {
+			oldItem = d.old.Paths[p]
+			if path == "" {
+				path = p
+			}
+		}
+		if p, ok := newPaths[k]; ok {
+			newItem = d.new.Paths[p]
+		}
+		for _, method := range methods {
+			var a, b *openapi3.Operation
+			if oldItem != nil {
+				a = oldItem.GetOperation(method)
+			}
+			if newItem != nil {
+				b = newItem.GetOperation(method)
+			}
+			d.op = method + " " + path
+			switch {
+			case a == nil && b == nil:
+			case b == nil:
+				d.add(Breaking, "operation-removed", "operation was removed")
+			case a == nil:
+				d.add(NonBreaking, "operation-added", "operation was added")
+			default:
+				d.operation(oldItem, newItem, a, b)
+			}
+		}
+	}
+	d.op = ""
+}
+
+func (d *differ) operation(oldItem, newItem *openapi3.PathItem, a, b *openapi3.Operation) {
+	if a.OperationID != b.OperationID {
+		d.add(NonBreaking, "operation-id-changed", "operationId changed from %v to %v", a.OperationID, b.OperationID)
+	}
+	if !a.Deprecated && b.Deprecated {
+		d.add(NonBreaking, "operation-deprecated", "operation was deprecated")
+	}
+	d.parameters(append(append(openapi3.Parameters{}, oldItem.Parameters...), a.Parameters...),
+		append(append(openapi3.Parameters{}, newItem.Parameters...), b.Parameters...))
+	d.requestBody(d.requestBodyOf(d.old, a.RequestBody), d.requestBodyOf(d.new, b.RequestBody))
+	d.responses(a.Responses, b.Responses)
+}
+
+func (d *differ) parameters(a, b openapi3.Parameters) {
+	type key struct{ in, name string }
+	params := func(t *openapi3.T, ps openapi3.Parameters) (map[key]*openapi3.Parameter, []key) {
+		m := map[key]*openapi3.Parameter{}
+		keys := []key{}
+		n := 0
+		for _, ref := range ps {
+			p := d.parameterOf(t, ref)
+			if p == nil {
+				continue
+			}
+			k := key{p.In, p.Name}
+			if p.In == "path" {
+				// by the position in the path instead of the name.
+				k.name = fmt.Sprint(n)
+				n++
+			}
+			if _, ok := m[k]; !ok {
+				keys = append(keys, k)
+			}
+			m[k] = p
+		}
+		return m, keys
+	}
+	oldParams, oldKeys := params(d.old, a)
+	newParams, newKeys := params(d.new, b)
+	for _, k := range oldKeys {
+		p := oldParams[k]
+		q, ok := newParams[k]
+		if !ok {
+			if k.in != "path" {
+				d.add(NonBreaking, "parameter-removed", "%v parameter %v was removed", p.In, p.Name)
+			}
+			continue
+		}
+		where := fmt.Sprintf("%v parameter %v", q.In, q.Name)
+		if !p.Required && q.Required {
+			d.add(Breaking, "parameter-became-required", "%v became required", where)
+		}
+		if p.Required && !q.Required {
+			d.add(NonBreaking, "parameter-became-optional", "%v became optional", where)
+		}
+		d.schema(where, p.Schema, q.Schema, false)
+	}
+	for _, k := range newKeys {
+		if _, ok := oldParams[k]; ok {
+			continue
+		}
+		q := newParams[k]
+		if q.Required {
+			d.add(Breaking, "required-parameter-added", "required %v parameter %v was added", q.In, q.Name)
+		} else {
+			d.add(NonBreaking, "parameter-added", "%v parameter %v was added", q.In, q.Name)
+		}
+	}
+}
+
+func (d *differ) requestBody(a, b *openapi3.RequestBody) {
+	switch {
+	case a == nil && b == nil:
+		return
+	case b == nil:
+		d.add(NonBreaking, "request-body-removed", "request body was removed")
+		return
+	case a == nil:
+		if b.Required {
+			d.add(Breaking, "required-request-body-added", "required request body was added")
+		} else {
+			d.add(NonBreaking, "request-body-added", "request body was added")
+		}
+		return
+	}
+	if !a.Required && b.Required {
+		d.add(Breaking, "request-body-became-required", "request body became required")
+	}
+	for _, ct := range sortedContent(a.Content) {
+		mt := b.Content[ct]
+		if mt == nil {
+			d.add(Breaking, "request-media-type-removed", "request body %v was removed", ct)
+			continue
+		}
+		d.schema("request body", a.Content[ct].Schema, mt.Schema, false)
+	}
+	for _, ct := range sortedContent(b.Content) {
+		if a.Content[ct] == nil {
+			d.add(NonBreaking, "request-media-type-added", "request body %v was added", ct)
+		}
+	}
+}
+
+func (d *differ) responses(a, b openapi3.Responses) {
+	codes := []string{}
+	for code := range a {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		oldRes := d.responseOf(d.old, a[code])
+		ref, ok := b[code]
+		if !ok {
+			if strings.HasPrefix(code, "2") {
+				d.add(Breaking, "response-removed", "response %v was removed", code)
+			} else {
+				d.add(NonBreaking, "response-removed", "response %v was removed", code)
+			}
+			continue
+		}
+		newRes := d.responseOf(d.new, ref)
+		if oldRes == nil || newRes == nil {
+			continue
+		}
+		for _, ct := range sortedContent(oldRes.Content) {
+			mt := newRes.Content[ct]
+			if mt == nil {
+				d.add(Breaking, "response-media-type-removed", "response %v %v was removed", code, ct)
+				continue
+			}
+			d.schema("response "+code, oldRes.Content[ct].Schema, mt.Schema, true)
+		}
+	}
+	codes = codes[:0]
+	for code := range b {
+		if _, ok := a[code]; !ok {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		d.add(NonBreaking, "response-added", "response %v was added", code)
+	}
+}
+
+func (d *differ) parameterOf(t *openapi3.T, ref *openapi3.ParameterRef) *openapi3.Parameter {
+	if ref == nil {
+		return nil
+	}
+	if ref.Ref != "" {
+		if p := t.Components.Parameters[refName(ref.Ref)]; p != nil && p.Ref != ref.Ref {
+			return d.parameterOf(t, p)
+		}
+	}
+	return ref.Value
+}
+
+func (d *differ) requestBodyOf(t *openapi3.T, ref *openapi3.RequestBodyRef) *openapi3.RequestBody {
+	if ref == nil {
+		return nil
+	}
+	if ref.Ref != "" {
+		if b := t.Components.RequestBodies[refName(ref.Ref)]; b != nil && b.Ref != ref.Ref {
+			return d.requestBodyOf(t, b)
+		}
+	}
+	return ref.Value
+}
+
+func (d *differ) responseOf(t *openapi3.T, ref *openapi3.ResponseRef) *openapi3.Response {
+	if ref == nil {
+		return nil
+	}
+	if ref.Ref != "" {
+		if r := t.Components.Responses[refName(ref.Ref)]; r != nil && r.Ref != ref.Ref {
+			return d.responseOf(t, r)
+		}
+	}
+	return ref.Value
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// schemaOf returns the schema of ref, resolving the $ref in the components of t.
+func schemaOf(t *openapi3.T, ref *openapi3.SchemaRef) *openapi3.Schema {
+	for depth := 0; ref != nil && depth < 32; depth++ {
+		if ref.Ref == "" || ref.Value != nil {
+			return ref.Value
+		}
+		ref = t.Components.Schemas[refName(ref.Ref)]
+	}
+	return nil
+}
+
+func sortedContent(c openapi3.Content) []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// properties returns the properties and the required properties of s with its allOf.
+func properties(t *openapi3.T, s *openapi3.Schema, depth int) (openapi3.Schemas, map[string]bool) {
+	props := openapi3.Schemas{}
+	required := map[string]bool{}
+	if s == nil || depth > 32 {
+		return props, required
+	}
+	for _, sub := range s.AllOf {
+		p, r := properties(t, schemaOf(t, sub), depth+1)
+		for k, v := range p {
+			props[k] = v
+		}
+		for k := range r {
+			required[k] = true
+		}
+	}
+	for k, v := range s.Properties {
+		props[k] = v
+	}
+	for _, k := range s.Required {
+		required[k] = true
+	}
+	return props, required
+}
+
+func typeOf(s *openapi3.Schema) string {
+	if s.Type != "" {
+		return s.Type
+	}
+	if len(s.AllOf) > 0 || len(s.Properties) > 0 {
+		return "object"
+	}
+	return ""
+}
+
+// schema compares the schemas at where, of a request if not response.
+// A request breaks when it is narrowed, a response when it is widened or its properties are removed.
+func (d *differ) schema(where string, a, b *openapi3.SchemaRef, response bool) {
+	if a == nil || b == nil {
+		return
+	}
+	direction := d.direction
+	d.direction = "request"
+	if response {
+		d.direction = "response"
+	}
+	defer func() {
+		d.direction = direction
+	}()
+	if a.Ref != "" && b.Ref != "" {
+		key := fmt.Sprintf("%v %v %v", a.Ref, b.Ref, response)
+		if d.visited[key] {
+			return
+		}
+		d.visited[key] = true
+		if refName(a.Ref) == refName(b.Ref) {
+			// the changes of a component are reported once, not for each operation.
+			// a component used by requests and responses is compared for each, told by the direction.
+			where = d.direction + " schema " + refName(b.Ref)
+			op, schemaName, property := d.op, d.schemaName, d.property
+			d.op, d.schemaName, d.property = "", refName(b.Ref), ""
+			defer func() {
+				d.op, d.schemaName, d.property = op, schemaName, property
+			}()
+		}
+	}
+	s := schemaOf(d.old, a)
+	u := schemaOf(d.new, b)
+	if s == nil || u == nil {
+		return
+	}
+
+	oldType := typeOf(s)
+	newType := typeOf(u)
+	if oldType != newType {
+		d.add(Breaking, "type-changed", "%v: type changed from %v to %v", where, orAny(oldType), orAny(newType))
+		return
+	}
+	if !s.Deprecated && u.Deprecated {
+		d.add(NonBreaking, "deprecated", "%v was deprecated", where)
+	}
+	if s.Format != u.Format {
+		d.add(Breaking, "format-changed", "%v: format changed from %v to %v", where, orNone(s.Format), orNone(u.Format))
+	}
+
+	// the old clients send the values of the old request, and accept the values of the old response.
+	narrowed, widened := Breaking, NonBreaking
+	if response {
+		narrowed, widened = NonBreaking, Breaking
+	}
+	if s.Nullable != u.Nullable {
+		if u.Nullable {
+			sev := NonBreaking
+			if response {
+				sev = Breaking
+			}
+			d.add(sev, "nullable-added", "%v became nullable", where)
+		} else {
+			d.add(narrowed, "nullable-removed", "%v became not nullable", where)
+		}
+	}
+	d.enum(where, s.Enum, u.Enum, response)
+	d.lower(where, "minimum", s.Min, u.Min, narrowed, widened)
+	d.upper(where, "maximum", s.Max, u.Max, narrowed, widened)
+	if s.ExclusiveMin != u.ExclusiveMin || s.ExclusiveMax != u.ExclusiveMax {
+		sev := widened
+		if (!s.ExclusiveMin && u.ExclusiveMin) || (!s.ExclusiveMax && u.ExclusiveMax) {
+			sev = narrowed
+		}
+		d.add(sev, "exclusive-changed", "%v: exclusiveMinimum/exclusiveMaximum changed", where)
+	}
+	d.lower(where, "minLength", uintPtr(s.MinLength), uintPtr(u.MinLength), narrowed, widened)
+	d.upper(where, "maxLength", floatPtr(s.MaxLength), floatPtr(u.MaxLength), narrowed, widened)
+	d.lower(where, "minItems", uintPtr(s.MinItems), uintPtr(u.MinItems), narrowed, widened)
+	d.upper(where, "maxItems", floatPtr(s.MaxItems), floatPtr(u.MaxItems), narrowed, widened)
+	if s.Pattern != u.Pattern {
+		// a changed pattern may be narrowed and widened.
+		sev := Breaking
+		switch {
+		case s.Pattern == "":
+			sev = narrowed
+		case u.Pattern == "":
+			sev = widened
+		}
+		d.add(sev, "pattern-changed", "%v: pattern changed from %v to %v", where, orNone(s.Pattern), orNone(u.Pattern))
+	}
+
+	d.members(where, "oneOf", s.OneOf, u.OneOf, narrowed, widened, response)
+	d.members(where, "anyOf", s.AnyOf, u.AnyOf, narrowed, widened, response)
+	if s.Items != nil || u.Items != nil {
+		d.schema(where+" items", s.Items, u.Items, response)
+	}
+	if s.AdditionalProperties != nil && u.AdditionalProperties != nil {
+		d.schema(where+" values", s.AdditionalProperties, u.AdditionalProperties, response)
+	}
+	if oldType == "object" {
+		d.object(where, s, u, response)
+	}
+}
+
+func (d *differ) object(where string, s, u *openapi3.Schema, response bool) {
+	parent := d.property
+	defer func() {
+		d.property = parent
+	}()
+	path := func(name string) string {
+		if parent == "" {
+			return name
+		}
+		return parent + "." + name
+	}
+	oldProps, oldRequired := properties(d.old, s, 0)
+	newProps, newRequired := properties(d.new, u, 0)
+	names := []string{}
+	for name := range oldProps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop := fmt.Sprintf("%v property %v", where, name)
+		d.property = path(name)
+		b, ok := newProps[name]
+		if !ok {
+			if response {
+				d.add(Breaking, "property-removed", "%v was removed", prop)
+			} else {
+				d.add(NonBreaking, "property-removed", "%v was removed", prop)
+			}
+			continue
+		}
+		switch {
+		case !oldRequired[name] && newRequired[name] && !response:
+			d.add(Breaking, "property-became-required", "%v became required", prop)
+		case oldRequired[name] && !newRequired[name] && response:
+			d.add(Breaking, "property-became-optional", "%v became optional", prop)
+		case oldRequired[name] != newRequired[name]:
+			d.add(NonBreaking, "property-required-changed", "%v became %v", prop, requiredText(newRequired[name]))
+		}
+		d.schema(prop, oldProps[name], b, response)
+	}
+	names = names[:0]
+	for name := range newProps {
+		if _, ok := oldProps[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop := fmt.Sprintf("%v property %v", where, name)
+		d.property = path(name)
+		if newRequired[name] && !response {
+			d.add(Breaking, "required-property-added", "%v was added as required", prop)
+		} else {
+			d.add(NonBreaking, "property-added", "%v was added", prop)
+		}
+	}
+}
+
+// members compares the members of oneOf or anyOf, matched by the names of the $refs or else by the positions.
+// A removed member narrows, a new member widens.
+func (d *differ) members(where, kind string, a, b openapi3.SchemaRefs, narrowed, widened Severity, response bool) {
+	key := func(i int, r *openapi3.SchemaRef) string {
+		if r.Ref != "" {
+			return refName(r.Ref)
+		}
+		return fmt.Sprint(i)
+	}
+	newMembers := map[string]*openapi3.SchemaRef{}
+	for i, r := range b {
+		newMembers[key(i, r)] = r
+	}
+	oldMembers := map[string]bool{}
+	for i, r := range a {
+		k := key(i, r)
+		oldMembers[k] = true
+		u, ok := newMembers[k]
+		if !ok {
+			d.add(narrowed, kind+"-member-removed", "%v: %v member %v was removed", where, kind, k)
+			continue
+		}
+		d.schema(fmt.Sprintf("%v %v %v", where, kind, k), r, u, response)
+	}
+	for i, r := range b {
+		k := key(i, r)
+		if !oldMembers[k] {
+			d.add(widened, kind+"-member-added", "%v: %v member %v was added", where, kind, k)
+		}
+	}
+}
+
+func (d *differ) enum(where string, a, b []interface{}, response bool) {
+	if reflect.DeepEqual(a, b) {
+		return
+	}
+	has := func(values []interface{}, v interface{}) bool {
+		for _, x := range values {
+			if reflect.DeepEqual(x, v) || fmt.Sprint(x) == fmt.Sprint(v) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(a) == 0 {
+		sev := Breaking
+		if response {
+			sev = NonBreaking
+		}
+		d.add(sev, "enum-added", "%v: enum was added", where)
+		return
+	}
+	if len(b) == 0 {
+		sev := NonBreaking
+		if response {
+			sev = Breaking
+		}
+		d.add(sev, "enum-removed", "%v: enum was removed", where)
+		return
+	}
+	for _, v := range a {
+		if !has(b, v) {
+			sev := Breaking
+			if response {
+				sev = NonBreaking
+			}
+			d.add(sev, "enum-value-removed", "%v: enum value %v was removed", where, v)
+		}
+	}
+	for _, v := range b {
+		if !has(a, v) {
+			sev := NonBreaking
+			if response {
+				sev = Breaking
+			}
+			d.add(sev, "enum-value-added", "%v: enum value %v was added", where, v)
+		}
+	}
+}
+
+// lower compares a lower bound, raising or adding it narrows.
+func (d *differ) lower(where, name string, a, b *float64, narrowed, widened Severity) {
+	switch {
+	case a == nil && b == nil:
+	case a == nil || (b != nil && *b > *a):
+		d.add(narrowed, name+"-increased", "%v: %v changed from %v to %v", where, name, bound(a), bound(b))
+	case b == nil || *b < *a:
+		d.add(widened, name+"-decreased", "%v: %v changed from %v to %v", where, name, bound(a), bound(b))
+	}
+}
+
+// upper compares an upper bound, lowering or adding it narrows.
+func (d *differ) upper(where, name string, a, b *float64, narrowed, widened Severity) {
+	switch {
+	case a == nil && b == nil:
+	case a == nil || (b != nil && *b < *a):
+		d.add(narrowed, name+"-decreased", "%v: %v changed from %v to %v", where, name, bound(a), bound(b))
+	case b == nil || *b > *a:
+		d.add(widened, name+"-increased", "%v: %v changed from %v to %v", where, name, bound(a), bound(b))
+	}
+}
+
+func uintPtr(v uint64) *float64 {
+	if v == 0 {
+		return nil
+	}
+	f := float64(v)
+	return &f
+}
+
+func floatPtr(v *uint64) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
+
+func bound(v *float64) string {
+	if v == nil {
+		return "none"
+	}
+	return fmt.Sprint(*v)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+func orAny(s string) string {
+	if s == "" {
+		return "any"
+	}
+	return s
+}
+
+func requiredText(required bool) string {
+	if required {
+		return "required"
+	}
+	return "optional"
+}
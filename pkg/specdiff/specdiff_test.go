@@ -0,0 +1,420 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package specdiff_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/require"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/specdiff"
+)
+
+const oldSrc = `
+openapi: 3.0.0
+info: {title: pets, version: 1.0.0}
+paths:
+  /pets:
+    get:
+      operationId: findPets
+      parameters:
+      - {in: query, name: limit, schema: {type: integer, maximum: 100}}
+      - {in: query, name: tag, schema: {type: string}}
+      responses:
+        "200":
+          description: pets
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: '#/components/schemas/Pet'}}
+    post:
+      operationId: addPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/NewPet'}
+      responses:
+        "200": {description: pet}
+  /pets/{id}:
+    delete:
+      parameters:
+      - {in: path, name: id, required: true, schema: {type: integer}}
+      responses:
+        "204": {description: deleted}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id: {type: integer}
+        name: {type: string}
+        kind: {type: string, enum: [dog, cat]}
+        age: {type: integer}
+    NewPet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string, maxLength: 64}
+        kind: {type: string, enum: [dog, cat]}
+`
+
+const newSrc = `
+openapi: 3.0.0
+info: {title: pets, version: 2.0.0}
+paths:
+  /pets:
+    get:
+      operationId: findPets
+      parameters:
+      - {in: query, name: limit, required: true, schema: {type: integer, maximum: 50}}
+      - {in: query, name: tag, schema: {type: string}}
+      - {in: query, name: owner, schema: {type: string}}
+      responses:
+        "200":
+          description: pets
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: '#/components/schemas/Pet'}}
+    post:
+      operationId: addPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/NewPet'}
+      responses:
+        "200": {description: pet}
+  /pets/{petId}:
+    get:
+      parameters:
+      - {in: path, name: petId, required: true, schema: {type: integer}}
+      responses:
+        "200": {description: pet}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id: {type: string}
+        name: {type: string}
+        kind: {type: string, enum: [dog, cat, bird]}
+    NewPet:
+      type: object
+      required: [name, owner]
+      properties:
+        name: {type: string, maxLength: 32}
+        kind: {type: string, enum: [dog]}
+        owner: {type: string}
+`
+
+func load(t *testing.T, src string) *openapi3.T {
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(src))
+	require.NoError(t, err)
+	return spec
+}
+
+func TestCompare(t *testing.T) {
+	report := specdiff.Compare(load(t, oldSrc), load(t, newSrc))
+	lines := []string{}
+	for _, c := range report.Changes {
+		lines = append(lines, c.String())
+	}
+	require.Equal(t, []string{
+		"non-breaking: version changed from 1.0.0 to 2.0.0",
+		"breaking: GET /pets: query parameter limit became required",
+		"breaking: GET /pets: query parameter limit: maximum changed from 100 to 50",
+		"non-breaking: GET /pets: query parameter owner was added",
+		"breaking: response schema Pet property age was removed",
+		"breaking: response schema Pet property id: type changed from integer to string",
+		"breaking: response schema Pet property kind: enum value bird was added",
+		"breaking: request schema NewPet property kind: enum value cat was removed",
+		"breaking: request schema NewPet property name: maxLength changed from 64 to 32",
+		"breaking: request schema NewPet property owner was added as required",
+		"non-breaking: GET /pets/{petId}: operation was added",
+		"breaking: DELETE /pets/{petId}: operation was removed",
+	}, lines)
+	require.Equal(t, 9, report.Count(specdiff.Breaking))
+	require.Equal(t, 3, report.Count(specdiff.NonBreaking))
+
+	require.Empty(t, specdiff.Compare(load(t, oldSrc), load(t, oldSrc)).Changes)
+
+	// loosening the request is not breaking.
+	report = specdiff.Compare(load(t, newSrc), load(t, oldSrc))
+	require.Contains(t, report.Changes, specdiff.Change{
+		Severity:  specdiff.NonBreaking,
+		Kind:      "maxLength-increased",
+		Schema:    "NewPet",
+		Property:  "name",
+		Direction: "request",
+		Message:   "request schema NewPet property name: maxLength changed from 32 to 64",
+	})
+	require.Contains(t, report.Changes, specdiff.Change{
+		Severity:  specdiff.NonBreaking,
+		Kind:      "enum-value-removed",
+		Schema:    "Pet",
+		Property:  "kind",
+		Direction: "response",
+		Message:   "response schema Pet property kind: enum value bird was removed",
+	})
+}
+
+func TestWrite(t *testing.T) {
+	report := specdiff.Compare(load(t, oldSrc), load(t, newSrc))
+
+	b := bytes.Buffer{}
+	require.NoError(t, report.Write(&b, specdiff.FormatText))
+	require.Contains(t, b.String(), "breaking: GET /pets: query parameter limit became required\n")
+
+	b.Reset()
+	require.NoError(t, report.Write(&b, specdiff.FormatJSON))
+	var obj struct {
+		Breaking    int
+		NonBreaking int
+		Changes     []specdiff.Change
+	}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &obj))
+	require.Equal(t, 9, obj.Breaking)
+	require.Equal(t, 3, obj.NonBreaking)
+	require.Equal(t, report.Changes, obj.Changes)
+
+	b.Reset()
+	require.NoError(t, report.Write(&b, specdiff.FormatMarkdown))
+	require.Contains(t, b.String(), "### Breaking changes (9)\n\n| Operation | Change |\n| --- | --- |\n"+
+		"| `GET /pets` | query parameter limit became required |\n")
+	require.Contains(t, b.String(), "### Non-breaking changes (3)\n")
+
+	b.Reset()
+	require.NoError(t, (&specdiff.Report{}).Write(&b, specdiff.FormatJSON))
+	require.Contains(t, b.String(), `"changes": []`)
+	require.Error(t, report.Write(&b, "xml"))
+}
+
+func TestWriteChangelog(t *testing.T) {
+	old := load(t, `
+openapi: 3.0.0
+info: {title: pets, version: 1.0.0}
+tags: [{name: pets}]
+paths:
+  /pets:
+    get:
+      tags: [pets]
+      summary: List pets
+      parameters:
+      - {in: query, name: limit, schema: {type: integer}}
+      responses:
+        "200": {description: pets}
+  /pets/{id}:
+    delete:
+      tags: [pets]
+      description: |-
+        Delete a pet.
+        The pet is removed.
+      parameters:
+      - {in: path, name: id, required: true, schema: {type: integer}}
+      responses:
+        "204": {description: deleted}
+  /health:
+    get:
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string}
+        tag: {type: string}
+        owner: {type: object, properties: {name: {type: string}}}
+`)
+	new := load(t, `
+openapi: 3.0.0
+info: {title: pets, version: 1.1.0}
+tags: [{name: pets}]
+paths:
+  /pets:
+    get:
+      tags: [pets]
+      summary: List pets
+      deprecated: true
+      parameters:
+      - {in: query, name: limit, required: true, schema: {type: integer}}
+      responses:
+        "200": {description: pets}
+  /pets/{id}/photos:
+    get:
+      tags: [pets]
+      summary: List the photos of a pet
+      parameters:
+      - {in: path, name: id, required: true, schema: {type: integer}}
+      responses:
+        "200": {description: photos}
+  /health:
+    get:
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string}
+        tag: {type: string, deprecated: true}
+        age: {type: integer}
+        owner: {type: object, properties: {name: {type: string, deprecated: true}}}
+`)
+	b := bytes.Buffer{}
+	require.NoError(t, specdiff.Compare(old, new).WriteChangelog(&b, old, new, "Changelog"))
+	require.Equal(t, "# Changelog\n\n"+
+		"- Version changed from 1.0.0 to 1.1.0\n\n"+
+		"## pets\n\n"+
+		"- Deprecated `GET /pets`: List pets\n"+
+		"- Changed `GET /pets`: List pets\n"+
+		"  - query parameter limit became required **(breaking)**\n"+
+		"- Removed `DELETE /pets/{id}`: Delete a pet. **(breaking)**\n"+
+		"- Added `GET /pets/{id}/photos`: List the photos of a pet\n\n"+
+		"## Schemas\n\n"+
+		"- Deprecated field Pet.owner.name\n"+
+		"- Deprecated field Pet.tag\n"+
+		"- Changed schema `Pet`\n"+
+		"  - response: property age was added\n", b.String())
+
+	b.Reset()
+	require.NoError(t, (&specdiff.Report{}).WriteChangelog(&b, old, old, ""))
+	require.Equal(t, "No changes of the API.\n", b.String())
+}
+
+func TestCompareDirections(t *testing.T) {
+	spec := func(pet string) *openapi3.T {
+		return load(t, `
+openapi: 3.0.0
+info: {title: pets, version: 1.0.0}
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: pets
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/Pet'}
+      responses:
+        "200": {description: pet}
+components:
+  schemas:
+    Pet: `+pet+`
+`)
+	}
+	old := spec("{type: object, properties: {name: {type: string}}}")
+	new := spec("{type: object, required: [age], properties: {name: {type: string, deprecated: true}, age: {type: integer}}}")
+	report := specdiff.Compare(old, new)
+	lines := []string{}
+	for _, c := range report.Changes {
+		lines = append(lines, fmt.Sprintf("%v %v %v", c.Direction, c.Severity, c.Message))
+	}
+	require.Equal(t, []string{
+		"response non-breaking response schema Pet property name was deprecated",
+		"response non-breaking response schema Pet property age was added",
+		"request non-breaking request schema Pet property name was deprecated",
+		"request breaking request schema Pet property age was added as required",
+	}, lines)
+
+	b := bytes.Buffer{}
+	require.NoError(t, report.WriteChangelog(&b, old, new, ""))
+	require.Equal(t, "## Schemas\n\n"+
+		"- Deprecated field Pet.name\n"+
+		"- Changed schema `Pet`\n"+
+		"  - response: property age was added\n"+
+		"  - request: property age was added as required **(breaking)**\n", b.String())
+}
+
+// constraintSpec returns a spec with the schema as the response of GET and the request of POST.
+func constraintSpec(t *testing.T, schema string) *openapi3.T {
+	return load(t, `
+openapi: 3.0.0
+info: {title: pets, version: 1.0.0}
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: pets
+          content:
+            application/json:
+              schema: `+schema+`
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema: `+schema+`
+      responses:
+        "200": {description: pet}
+components:
+  schemas:
+    Cat: {type: object, properties: {name: {type: string}}}
+    Dog: {type: object, properties: {name: {type: string}}}
+`)
+}
+
+func TestCompareConstraints(t *testing.T) {
+	for _, c := range []struct {
+		old, new string
+		// the severities of the change of the response and the request
+		response, request specdiff.Severity
+	}{
+		{"{type: integer, maximum: 10}", "{type: integer, maximum: 20}", specdiff.Breaking, specdiff.NonBreaking},
+		{"{type: integer, maximum: 20}", "{type: integer, maximum: 10}", specdiff.NonBreaking, specdiff.Breaking},
+		{"{type: integer, minimum: 10}", "{type: integer, minimum: 0}", specdiff.Breaking, specdiff.NonBreaking},
+		{"{type: integer, minimum: 0}", "{type: integer, minimum: 10}", specdiff.NonBreaking, specdiff.Breaking},
+		{"{type: string, maxLength: 10}", "{type: string, maxLength: 20}", specdiff.Breaking, specdiff.NonBreaking},
+		{"{type: string, maxLength: 10}", "{type: string}", specdiff.Breaking, specdiff.NonBreaking},
+		{"{type: string, minLength: 5}", "{type: string, minLength: 1}", specdiff.Breaking, specdiff.NonBreaking},
+		{"{type: array, items: {type: string}, maxItems: 10}", "{type: array, items: {type: string}, maxItems: 20}", specdiff.Breaking, specdiff.NonBreaking},
+		{"{type: array, items: {type: string}, minItems: 2}", "{type: array, items: {type: string}, minItems: 1}", specdiff.Breaking, specdiff.NonBreaking},
+		{"{type: string, pattern: '^[a-z]+$'}", "{type: string}", specdiff.Breaking, specdiff.NonBreaking},
+		{"{type: string}", "{type: string, pattern: '^[a-z]+$'}", specdiff.NonBreaking, specdiff.Breaking},
+		{"{type: string, pattern: '^[a-z]+$'}", "{type: string, pattern: '^[0-9]+$'}", specdiff.Breaking, specdiff.Breaking},
+		{"{type: integer, maximum: 10, exclusiveMaximum: true}", "{type: integer, maximum: 10}", specdiff.Breaking, specdiff.NonBreaking},
+		{"{oneOf: [$ref: '#/components/schemas/Cat']}", "{oneOf: [$ref: '#/components/schemas/Cat', $ref: '#/components/schemas/Dog']}", specdiff.Breaking, specdiff.NonBreaking},
+		{"{anyOf: [$ref: '#/components/schemas/Cat', $ref: '#/components/schemas/Dog']}", "{anyOf: [$ref: '#/components/schemas/Dog']}", specdiff.NonBreaking, specdiff.Breaking},
+		{"{oneOf: [{type: string, maxLength: 5}, {type: integer}]}", "{oneOf: [{type: string, maxLength: 10}, {type: integer}]}", specdiff.Breaking, specdiff.NonBreaking},
+	} {
+		report := specdiff.Compare(constraintSpec(t, c.old), constraintSpec(t, c.new))
+		severities := map[string]specdiff.Severity{}
+		for _, change := range report.Changes {
+			severities[change.Operation] = change.Severity
+		}
+		require.Len(t, report.Changes, 2, "%v -> %v: %v", c.old, c.new, report.Changes)
+		require.Equal(t, c.response, severities["GET /pets"], "response %v -> %v", c.old, c.new)
+		require.Equal(t, c.request, severities["POST /pets"], "request %v -> %v", c.old, c.new)
+	}
+}
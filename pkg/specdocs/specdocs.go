@@ -0,0 +1,241 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package specdocs writes the Markdown document of a spec.
+package specdocs
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+var methodOrder = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"}
+
+// Markdown returns the document of the operations and the schemas of spec.
+func Markdown(spec *openapi3.T) []byte {
+	w := &bytes.Buffer{}
+	title := "API"
+	if spec.Info != nil {
+		title = spec.Info.Title
+		if spec.Info.Version != "" {
+			title += " " + spec.Info.Version
+		}
+	}
+	fmt.Fprintf(w, "# %v\n\n", title)
+	if spec.Info != nil && spec.Info.Description != "" {
+		fmt.Fprintf(w, "%v\n\n", strings.TrimSpace(spec.Info.Description))
+	}
+	if len(spec.Servers) > 0 {
+		w.WriteString("Servers:\n\n")
+		for _, s := range spec.Servers {
+			fmt.Fprintf(w, "- `%v` %v\n", s.URL, s.Description)
+		}
+		w.WriteString("\n")
+	}
+
+	w.WriteString("## Operations\n\n")
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := spec.Paths[path]
+		for _, method := range methodOrder {
+			ope := item.GetOperation(method)
+			if ope != nil {
+				writeOperation(w, method, path, ope)
+			}
+		}
+	}
+
+	if len(spec.Components.Schemas) > 0 {
+		w.WriteString("## Schemas\n\n")
+		names := make([]string, 0, len(spec.Components.Schemas))
+		for name := range spec.Components.Schemas {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			writeSchema(w, name, spec.Components.Schemas[name])
+		}
+	}
+	return bytes.TrimRight(w.Bytes(), "\n")
+}
+
+func writeOperation(w *bytes.Buffer, method, path string, ope *openapi3.Operation) {
+	fmt.Fprintf(w, "### %v %v\n\n", method, path)
+	if ope.OperationID != "" {
+		fmt.Fprintf(w, "`%v`", ope.OperationID)
+		if len(ope.Tags) > 0 {
+			fmt.Fprintf(w, " tags: %v", strings.Join(ope.Tags, ", "))
+		}
+		w.WriteString("\n\n")
+	}
+	if ope.Deprecated {
+		w.WriteString("**Deprecated**\n\n")
+	}
+	doc := strings.TrimSpace(ope.Summary + "\n\n" + ope.Description)
+	if doc != "" {
+		fmt.Fprintf(w, "%v\n\n", doc)
+	}
+	if len(ope.Parameters) > 0 {
+		w.WriteString("| Parameter | In | Type | Required | Description |\n")
+		w.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, p := range ope.Parameters {
+			if p.Value == nil {
+				continue
+			}
+			fmt.Fprintf(w, "| %v | %v | %v | %v | %v |\n", p.Value.Name, p.Value.In, typeOf(p.Value.Schema), yesNo(p.Value.Required), cell(p.Value.Description))
+		}
+		w.WriteString("\n")
+	}
+	if ope.RequestBody != nil && ope.RequestBody.Value != nil {
+		for _, ct := range sortedContent(ope.RequestBody.Value.Content) {
+			fmt.Fprintf(w, "Request body `%v`: %v\n\n", ct, typeOf(ope.RequestBody.Value.Content[ct].Schema))
+		}
+	}
+	codes := make([]string, 0, len(ope.Responses))
+	for code := range ope.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	w.WriteString("| Response | Type | Description |\n")
+	w.WriteString("| --- | --- | --- |\n")
+	for _, code := range codes {
+		res := ope.Responses[code].Value
+		if res == nil {
+			continue
+		}
+		t := ""
+		mt := res.Content.Get("application/json")
+		if mt != nil {
+			t = typeOf(mt.Schema)
+		}
+		desc := ""
+		if res.Description != nil {
+			desc = *res.Description
+		}
+		fmt.Fprintf(w, "| %v | %v | %v |\n", code, t, cell(desc))
+	}
+	w.WriteString("\n")
+}
+
+func writeSchema(w *bytes.Buffer, name string, ref *openapi3.SchemaRef) {
+	fmt.Fprintf(w, "### %v\n\n", name)
+	s := ref.Value
+	if s == nil {
+		return
+	}
+	if s.Description != "" {
+		fmt.Fprintf(w, "%v\n\n", strings.TrimSpace(s.Description))
+	}
+	for _, p := range s.AllOf {
+		fmt.Fprintf(w, "- allOf %v\n", typeOf(p))
+	}
+	for _, p := range s.OneOf {
+		fmt.Fprintf(w, "- oneOf %v\n", typeOf(p))
+	}
+	for _, p := range s.AnyOf {
+		fmt.Fprintf(w, "- anyOf %v\n", typeOf(p))
+	}
+	if len(s.AllOf)+len(s.OneOf)+len(s.AnyOf) > 0 {
+		w.WriteString("\n")
+	}
+	if len(s.Enum) > 0 {
+		values := []string{}
+		for _, v := range s.Enum {
+			values = append(values, fmt.Sprintf("`%v`", v))
+		}
+		fmt.Fprintf(w, "Enum: %v\n\n", strings.Join(values, ", "))
+	}
+	if len(s.Properties) == 0 {
+		if len(s.AllOf)+len(s.OneOf)+len(s.AnyOf)+len(s.Enum) == 0 {
+			fmt.Fprintf(w, "Type: %v\n\n", typeOf(ref))
+		}
+		return
+	}
+	required := map[string]bool{}
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w.WriteString("| Property | Type | Required | Description |\n")
+	w.WriteString("| --- | --- | --- | --- |\n")
+	for _, n := range names {
+		p := s.Properties[n]
+		desc := ""
+		if p.Value != nil {
+			desc = p.Value.Description
+		}
+		fmt.Fprintf(w, "| %v | %v | %v | %v |\n", n, typeOf(p), yesNo(required[n]), cell(desc))
+	}
+	w.WriteString("\n")
+}
+
+// typeOf returns the short type of a schema, linking the component schemas.
+func typeOf(ref *openapi3.SchemaRef) string {
+	if ref == nil {
+		return ""
+	}
+	if ref.Ref != "" {
+		name := ref.Ref[strings.LastIndex(ref.Ref, "/")+1:]
+		return fmt.Sprintf("[%v](#%v)", name, strings.ToLower(name))
+	}
+	s := ref.Value
+	if s == nil {
+		return ""
+	}
+	switch {
+	case s.Type == "array":
+		return typeOf(s.Items) + "[]"
+	case s.AdditionalProperties != nil:
+		return "map[" + typeOf(s.AdditionalProperties) + "]"
+	case s.Format != "":
+		return s.Type + "(" + s.Format + ")"
+	case s.Type != "":
+		return s.Type
+	}
+	return "any"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return ""
+}
+
+func cell(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func sortedContent(c openapi3.Content) []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package specdocs_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/require"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/specdocs"
+)
+
+func TestMarkdown(t *testing.T) {
+	spec, err := openapi3.NewLoader().LoadFromFile("../../testdata/pet.yaml")
+	require.NoError(t, err)
+
+	md := string(specdocs.Markdown(spec))
+	require.True(t, strings.HasPrefix(md, "# Swagger Petstore 1.0.0\n"))
+	require.Contains(t, md, "### GET /pets\n\n`findPets`\n")
+	require.Contains(t, md, "| limit | query | integer(int32) |  | maximum number of results to return |\n")
+	require.Contains(t, md, "| 200 | [Pet](#pet)[] | pet response |\n")
+	require.Contains(t, md, "### Pet\n\n- allOf [NewPet](#newpet)\n")
+	require.Contains(t, md, "| name | string | yes |  |\n")
+	require.True(t, strings.Index(md, "### GET /pets/{id}") < strings.Index(md, "### DELETE /pets/{id}"))
+}
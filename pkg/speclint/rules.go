@@ -0,0 +1,206 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package speclint
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Rules are the rules of Lint in the order of the checks.
+var Rules = []*Rule{
+	{"operation-id-casing", "operationIds are camelCase, all upper or all lower", Warning, checkOperationIDCasing},
+	{"operation-summary", "every operation has a summary", Warning, checkOperationSummary},
+	{"operation-description", "every operation has a description", Off, checkOperationDescription},
+	{"operation-tags", "every operation has a tag", Warning, checkOperationTags},
+	{"operation-4xx-response", "every operation documents a 4XX response", Warning, checkOperation4xxResponse},
+	{"response-inline-object", "the responses refer to the object schemas of components", Warning, checkResponseInlineObject},
+	{"path-casing", "the segments of the paths are kebab-case", Warning, checkPathCasing},
+	{"pagination-params", "the operations have the same pagination parameters", Warning, checkPaginationParams},
+}
+
+var camelCase = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+func checkOperationIDCasing(t *openapi3.T, report func(ptr, format string, args ...interface{})) {
+	opes := []*operation{}
+	upper := 0
+	for _, o := range operations(t) {
+		if o.OperationID == "" {
+			continue
+		}
+		if !camelCase.MatchString(o.OperationID) {
+			report(o.ptr, "%v: operationId %v is not camelCase", o, o.OperationID)
+			continue
+		}
+		if unicode.IsUpper(rune(o.OperationID[0])) {
+			upper++
+		}
+		opes = append(opes, o)
+	}
+	// the casing of the most operations, UpperCamelCase of the Go methods if even.
+	isUpper := upper*2 >= len(opes)
+	casing := "lowerCamelCase"
+	if isUpper {
+		casing = "UpperCamelCase"
+	}
+	for _, o := range opes {
+		if unicode.IsUpper(rune(o.OperationID[0])) != isUpper {
+			report(o.ptr, "%v: operationId %v is not %v like the others", o, o.OperationID, casing)
+		}
+	}
+}
+
+func checkOperationSummary(t *openapi3.T, report func(ptr, format string, args ...interface{})) {
+	for _, o := range operations(t) {
+		if o.Summary == "" {
+			report(o.ptr, "%v: no summary", o)
+		}
+	}
+}
+
+func checkOperationDescription(t *openapi3.T, report func(ptr, format string, args ...interface{})) {
+	for _, o := range operations(t) {
+		if o.Description == "" {
+			report(o.ptr, "%v: no description", o)
+		}
+	}
+}
+
+func checkOperationTags(t *openapi3.T, report func(ptr, format string, args ...interface{})) {
+	for _, o := range operations(t) {
+		if len(o.Tags) == 0 {
+			report(o.ptr, "%v: no tags", o)
+		}
+	}
+}
+
+func checkOperation4xxResponse(t *openapi3.T, report func(ptr, format string, args ...interface{})) {
+	for _, o := range operations(t) {
+		found := false
+		for code := range o.Responses {
+			if strings.HasPrefix(code, "4") {
+				found = true
+			}
+		}
+		if !found {
+			report(o.ptr, "%v: no 4XX response", o)
+		}
+	}
+}
+
+func checkResponseInlineObject(t *openapi3.T, report func(ptr, format string, args ...interface{})) {
+	for _, o := range operations(t) {
+		for _, code := range sortedKeys(o.Responses) {
+			res := o.Responses[code]
+			if res == nil || res.Value == nil {
+				continue
+			}
+			for _, mediaType := range sortedKeys(res.Value.Content) {
+				s := res.Value.Content[mediaType].Schema
+				if s != nil && s.Ref == "" && s.Value != nil && s.Value.Type == "array" {
+					s = s.Value.Items
+				}
+				if s != nil && s.Ref == "" && s.Value != nil && (s.Value.Type == "object" || len(s.Value.Properties) > 0) {
+					report(o.ptr, "%v: response %v %v is an inline object, declare it as a type", o, code, mediaType)
+				}
+			}
+		}
+	}
+}
+
+var kebabCase = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func checkPathCasing(t *openapi3.T, report func(ptr, format string, args ...interface{})) {
+	reported := map[string]bool{}
+	for _, o := range operations(t) {
+		if reported[o.path] {
+			continue
+		}
+		reported[o.path] = true
+		if o.path != "/" && strings.HasSuffix(o.path, "/") {
+			report(o.ptr, "path %v ends with /", o.path)
+		}
+		for _, seg := range strings.Split(strings.Trim(o.path, "/"), "/") {
+			if seg == "" || strings.HasPrefix(seg, "{") {
+				continue
+			}
+			if !kebabCase.MatchString(seg) {
+				report(o.ptr, "path %v: %v is not kebab-case", o.path, seg)
+			}
+		}
+	}
+}
+
+var paginationParams = map[string]bool{
+	"limit": true, "offset": true, "page": true, "pageSize": true, "perPage": true,
+	"page_size": true, "per_page": true, "cursor": true, "after": true, "before": true,
+}
+
+func checkPaginationParams(t *openapi3.T, report func(ptr, format string, args ...interface{})) {
+	type paged struct {
+		*operation
+		params string
+	}
+	opes := []paged{}
+	counts := map[string]int{}
+	for _, o := range operations(t) {
+		names := []string{}
+		for _, p := range append(append(openapi3.Parameters{}, t.Paths[o.path].Parameters...), o.Parameters...) {
+			if p.Value != nil && p.Value.In == openapi3.ParameterInQuery && paginationParams[p.Value.Name] {
+				names = append(names, p.Value.Name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+		params := strings.Join(names, ", ")
+		opes = append(opes, paged{o, params})
+		counts[params]++
+	}
+	// the parameters of the most operations, of the first if even.
+	common := ""
+	for _, o := range opes {
+		if counts[o.params] > counts[common] {
+			common = o.params
+		}
+	}
+	for _, o := range opes {
+		if o.params != common {
+			report(o.ptr, "%v: pagination parameters %v differ from %v of the others", o.operation, o.params, common)
+		}
+	}
+}
+
+// sortedKeys returns the sorted keys of the responses or the content.
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch o := m.(type) {
+	case openapi3.Responses:
+		for k := range o {
+			keys = append(keys, k)
+		}
+	case openapi3.Content:
+		for k := range o {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
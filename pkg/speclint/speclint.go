@@ -0,0 +1,141 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package speclint checks the conventions of the generated specs by the rules,
+// reporting the problems at the Go declarations of spec.go.
+package speclint
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genspec"
+)
+
+// Severity of a rule, the same as the lint of the project file.
+const (
+	Off     = "off"
+	Warning = "warning"
+	Error   = "error"
+)
+
+// Rule checks a spec, calling report for each problem at the JSON pointer of the spec.
+type Rule struct {
+	Name     string
+	Doc      string
+	Severity string
+	Check    func(t *openapi3.T, report func(ptr, format string, args ...interface{}))
+}
+
+// Source maps the JSON pointers of the spec to spec.go, genspec.Generator implements it.
+type Source interface {
+	Position(ptr string) token.Position
+	Ignored(ptr, rule string) bool
+}
+
+// Lint checks t by the rules with the severities by the rule names, the default severities of the rules
+// otherwise. src positions the problems and suppresses them, it may be nil for the specs not from Go.
+func Lint(t *openapi3.T, src Source, severities map[string]string) ([]genspec.Diagnostic, error) {
+	known := map[string]bool{}
+	for _, r := range Rules {
+		known[r.Name] = true
+	}
+	names := make([]string, 0, len(severities))
+	for name := range severities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !known[name] {
+			return nil, errors.Errorf("unknown lint rule %q", name)
+		}
+	}
+
+	diags := []genspec.Diagnostic{}
+	for _, r := range Rules {
+		severity, ok := severities[r.Name]
+		if !ok {
+			severity = r.Severity
+		}
+		var s genspec.Severity
+		switch severity {
+		case Off:
+			continue
+		case Warning:
+			s = genspec.SeverityWarning
+		case Error:
+			s = genspec.SeverityError
+		default:
+			return nil, errors.Errorf("lint %v: unknown severity %q", r.Name, severity)
+		}
+		r.Check(t, func(ptr, format string, args ...interface{}) {
+			d := genspec.Diagnostic{
+				Severity: s,
+				Message:  fmt.Sprintf("%v (%v)", fmt.Sprintf(format, args...), r.Name),
+			}
+			if src != nil {
+				if src.Ignored(ptr, r.Name) {
+					return
+				}
+				d.Pos = src.Position(ptr)
+			}
+			diags = append(diags, d)
+		})
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Pos, diags[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	return diags, nil
+}
+
+// operation is an operation of the spec in the order of the paths and the methods.
+type operation struct {
+	method string
+	path   string
+	ptr    string
+	*openapi3.Operation
+}
+
+func (o *operation) String() string {
+	return o.method + " " + o.path
+}
+
+var methods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"}
+
+func operations(t *openapi3.T) []*operation {
+	paths := make([]string, 0, len(t.Paths))
+	for path := range t.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	opes := []*operation{}
+	for _, path := range paths {
+		for _, method := range methods {
+			ope := t.Paths[path].GetOperation(method)
+			if ope != nil {
+				ptr := genspec.Pointer("paths", path, strings.ToLower(method))
+				opes = append(opes, &operation{method, path, ptr, ope})
+			}
+		}
+	}
+	return opes
+}
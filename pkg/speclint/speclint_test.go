@@ -0,0 +1,172 @@
+// Copyright (c) 2021 uk-taniyama.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package speclint_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/require"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/genspec"
+	"github.com/uk-taniyama/go-openapi-spec/pkg/speclint"
+)
+
+const src = `
+openapi: 3.0.0
+info: {title: pets, version: 1.0.0}
+paths:
+  /pets:
+    get:
+      operationId: findPets
+      summary: List pets
+      tags: [pets]
+      parameters:
+      - {in: query, name: limit, schema: {type: integer}}
+      - {in: query, name: offset, schema: {type: integer}}
+      responses:
+        "200":
+          description: pets
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: '#/components/schemas/Pet'}}
+        "404": {description: not found}
+  /pets/{id}/Photos:
+    get:
+      operationId: find_photos
+      parameters:
+      - {in: path, name: id, required: true, schema: {type: integer}}
+      - {in: query, name: page, schema: {type: integer}}
+      responses:
+        "200":
+          description: photos
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  url: {type: string}
+  /owners:
+    get:
+      operationId: FindOwners
+      summary: List owners
+      tags: [owners]
+      parameters:
+      - {in: query, name: limit, schema: {type: integer}}
+      - {in: query, name: offset, schema: {type: integer}}
+      responses:
+        "400": {description: bad request}
+    post:
+      operationId: addOwner
+      summary: Add an owner
+      tags: [owners]
+      responses:
+        "4XX": {description: bad request}
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string}
+`
+
+func messages(diags []genspec.Diagnostic) []string {
+	lines := []string{}
+	for _, d := range diags {
+		lines = append(lines, d.String())
+	}
+	return lines
+}
+
+func TestLint(t *testing.T) {
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(src))
+	require.NoError(t, err)
+
+	diags, err := speclint.Lint(spec, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"warning: GET /pets/{id}/Photos: operationId find_photos is not camelCase (operation-id-casing)",
+		"warning: GET /owners: operationId FindOwners is not lowerCamelCase like the others (operation-id-casing)",
+		"warning: GET /pets/{id}/Photos: no summary (operation-summary)",
+		"warning: GET /pets/{id}/Photos: no tags (operation-tags)",
+		"warning: GET /pets/{id}/Photos: no 4XX response (operation-4xx-response)",
+		"warning: GET /pets/{id}/Photos: response 200 application/json is an inline object, declare it as a type (response-inline-object)",
+		"warning: path /pets/{id}/Photos: Photos is not kebab-case (path-casing)",
+		"warning: GET /pets/{id}/Photos: pagination parameters page differ from limit, offset of the others (pagination-params)",
+	}, messages(diags))
+
+	diags, err = speclint.Lint(spec, nil, map[string]string{
+		"operation-id-casing":   speclint.Off,
+		"operation-summary":     speclint.Error,
+		"operation-description": speclint.Warning,
+		"operation-tags":        speclint.Off,
+		"path-casing":           speclint.Off,
+		"pagination-params":     speclint.Off,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"error: GET /pets/{id}/Photos: no summary (operation-summary)",
+		"warning: GET /owners: no description (operation-description)",
+		"warning: POST /owners: no description (operation-description)",
+		"warning: GET /pets: no description (operation-description)",
+		"warning: GET /pets/{id}/Photos: no description (operation-description)",
+		"warning: GET /pets/{id}/Photos: no 4XX response (operation-4xx-response)",
+		"warning: GET /pets/{id}/Photos: response 200 application/json is an inline object, declare it as a type (response-inline-object)",
+	}, messages(diags))
+
+	_, err = speclint.Lint(spec, nil, map[string]string{"no-such-rule": speclint.Error})
+	require.EqualError(t, err, `unknown lint rule "no-such-rule"`)
+	_, err = speclint.Lint(spec, nil, map[string]string{"path-casing": "never"})
+	require.EqualError(t, err, `lint path-casing: unknown severity "never"`)
+}
+
+func TestLintSource(t *testing.T) {
+	g, err := genspec.NewGenerator(&genspec.Config{InputFile: "spec.go", Source: `package api
+
+const OpenAPISpec = ` + "`" + `
+info: {title: pets, version: 1.0.0}
+` + "`" + `
+
+type Error struct {
+	Message string ` + "`json:\"message\"`" + `
+}
+
+type Interface interface {
+	// (GET /pets)
+	// summary: List pets
+	// tags: [pets]
+	// 200: pets
+	// default: unexpected error
+	FindPets()
+
+	// (GET /pets/{id})
+	// 200: a pet
+	// default: unexpected error
+	//openapi:ignore operation-summary,operation-tags
+	FindPet(id int64)
+}
+`})
+	require.NoError(t, err)
+	spec, err := g.Generate(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, g.Diagnostics())
+
+	diags, err := speclint.Lint(spec, g, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"spec.go:17:2: warning: GET /pets: no 4XX response (operation-4xx-response)",
+		"spec.go:23:2: warning: GET /pets/{id}: no 4XX response (operation-4xx-response)",
+	}, messages(diags))
+}